@@ -3,6 +3,7 @@ package stack
 import (
 	"net/http"
 	"testing"
+	"time"
 )
 
 func mkRequestResponse() (w http.ResponseWriter, r *http.Request) {
@@ -100,3 +101,42 @@ func BenchmarkWrapping(b *testing.B) {
 	b.StartTimer()
 	s.Handler()
 }
+
+type noopInstrumenter struct{}
+
+func (noopInstrumenter) Enter(d *Descriptor, req *http.Request) {}
+
+func (noopInstrumenter) Exit(d *Descriptor, req *http.Request, status int, elapsed time.Duration) {}
+
+func BenchmarkServing50WrappersInstrumented(b *testing.B) {
+	b.StopTimer()
+	var s Stack
+	for i := 0; i < 50; i++ {
+		s.UseHandler(wr)
+	}
+	s.Instrument(noopInstrumenter{})
+	benchmark(s.Handler(), b)
+}
+
+func mkContextStack() *Stack {
+	var s Stack
+	s.UseWithContext(setCtx("a"))
+	s.UseFuncWithContext(writeCtxNext)
+	s.UseWithContext(appendCtx("b"))
+	return &s
+}
+
+func benchmarkAllocs(h http.Handler, b *testing.B) {
+	b.ReportAllocs()
+	benchmark(h, b)
+}
+
+func BenchmarkHandlerWithContext(b *testing.B) {
+	b.StopTimer()
+	benchmarkAllocs(mkContextStack().HandlerWithContext(), b)
+}
+
+func BenchmarkHandlerWithRequestContext(b *testing.B) {
+	b.StopTimer()
+	benchmarkAllocs(mkContextStack().HandlerWithRequestContext(), b)
+}
@@ -1,13 +1,15 @@
 package stack
 
 import (
+	stdcontext "context"
+	"io"
 	"net/http"
 	"reflect"
 	"sync"
 )
 
 type contextTransaction struct {
-	*context
+	*store
 }
 
 func (c *contextTransaction) Set(val Swapper) {
@@ -27,31 +29,138 @@ func (c *contextTransaction) Get(target Swapper) bool {
 	return true
 }
 
-var _ Contexter = &contextTransaction{}
+func (c *contextTransaction) SetValue(key, val interface{}) {
+	c.data[key] = val
+}
+
+func (c *contextTransaction) GetValue(key interface{}) (val interface{}, has bool) {
+	val, has = c.data[key]
+	return
+}
+
+func (c *contextTransaction) DelValue(key interface{}) {
+	delete(c.data, key)
+}
+
+func (c *contextTransaction) Range(fn func(key, val interface{}) bool) {
+	c.store.each(fn)
+}
+
+func (c *contextTransaction) Keys() []interface{} {
+	return c.store.keys()
+}
+
+var _ TransactionContexter = &contextTransaction{}
 var _ Contexter = &context{}
+var _ Contexter = &handle{}
 
-type context struct {
-	http.ResponseWriter // you always need this
-	data                map[interface{}]Swapper
-	*sync.RWMutex
+// responseWriterKey is the key of the ResponseWriter inside the store
+var responseWriterKey = reflect.TypeOf(&ResponseWriter{})
+
+// errReleased is the panic message if a Contexter is used after the request has been served
+const errReleased = "stack.Contexter used after the request has been served"
+
+// store keeps the per request data of a Contexter.
+// Swappers are stored by their type, other values by their key.
+// Stores are pooled and reused for the next request once a request has been served.
+type store struct {
+	sync.RWMutex
+	data map[interface{}]interface{}
+
+	// gen is the generation of the store that is incremented each time the store is released
+	gen uint64
+
+	// rw is stored inside data, so that it has not to be allocated for each request
+	rw ResponseWriter
+
+	hooks hooks
 }
 
-func (c *context) Set(val Swapper) {
-	c.Lock()
-	defer c.Unlock()
-	c.data[reflect.TypeOf(val)] = val
+var storePool = sync.Pool{
+	New: func() interface{} {
+		return &store{data: map[interface{}]interface{}{}}
+	},
 }
 
-func (c *context) Del(val Swapper) {
-	c.Lock()
-	defer c.Unlock()
-	delete(c.data, reflect.TypeOf(val))
+// acquireStore returns an empty store from the pool with the given ResponseWriter saved
+func acquireStore(wr http.ResponseWriter) *store {
+	s := storePool.Get().(*store)
+	s.rw.ResponseWriter = wr
+	s.data[responseWriterKey] = &s.rw
+	return s
 }
 
-func (c *context) Get(target Swapper) bool {
-	c.RLock()
-	defer c.RUnlock()
-	src, has := c.data[reflect.TypeOf(target)]
+// release empties the store and puts it back into the pool. Any handle of the current
+// generation panics when it is used afterwards.
+func (s *store) release() {
+	s.Lock()
+	for k := range s.data {
+		delete(s.data, k)
+	}
+	s.rw.ResponseWriter = nil
+	s.hooks.reset()
+	s.gen++
+	s.Unlock()
+	storePool.Put(s)
+}
+
+// each calls fn for each value until fn returns false. The caller must hold the lock
+func (s *store) each(fn func(key, val interface{}) bool) {
+	for k, v := range s.data {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// keys returns the keys of all values. The caller must hold the lock
+func (s *store) keys() []interface{} {
+	keys := make([]interface{}, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// handle is a Contexter that refers to a store for the duration of a single request.
+// It panics if it is used after the store has been released.
+type handle struct {
+	*store
+	gen uint64
+}
+
+func (h *handle) lock() {
+	h.Lock()
+	if h.store.gen != h.gen {
+		h.Unlock()
+		panic(errReleased)
+	}
+}
+
+func (h *handle) rlock() {
+	h.RLock()
+	if h.store.gen != h.gen {
+		h.RUnlock()
+		panic(errReleased)
+	}
+}
+
+func (h *handle) Set(val Swapper) {
+	h.lock()
+	defer h.Unlock()
+	h.data[reflect.TypeOf(val)] = val
+}
+
+func (h *handle) Del(val Swapper) {
+	h.lock()
+	defer h.Unlock()
+	delete(h.data, reflect.TypeOf(val))
+}
+
+func (h *handle) Get(target Swapper) bool {
+	h.rlock()
+	defer h.RUnlock()
+	src, has := h.data[reflect.TypeOf(target)]
 	if !has {
 		return false
 	}
@@ -59,18 +168,200 @@ func (c *context) Get(target Swapper) bool {
 	return true
 }
 
-func (c *context) Transaction(fn func(TransactionContexter)) {
-	c.Lock()
-	defer c.Unlock()
-	fn(&contextTransaction{c})
+func (h *handle) SetValue(key, val interface{}) {
+	h.lock()
+	defer h.Unlock()
+	h.data[key] = val
+}
+
+func (h *handle) GetValue(key interface{}) (val interface{}, has bool) {
+	h.rlock()
+	defer h.RUnlock()
+	val, has = h.data[key]
+	return
+}
+
+func (h *handle) DelValue(key interface{}) {
+	h.lock()
+	defer h.Unlock()
+	delete(h.data, key)
+}
+
+func (h *handle) Transaction(fn func(TransactionContexter)) {
+	h.lock()
+	defer h.Unlock()
+	fn(&contextTransaction{h.store})
+}
+
+func (h *handle) Snapshot() Contexter {
+	h.rlock()
+	defer h.RUnlock()
+	data := make(map[interface{}]interface{}, len(h.data))
+	for k, v := range h.data {
+		data[k] = v
+	}
+	delete(data, responseWriterKey)
+	return &handle{store: &store{data: data}}
+}
+
+func (h *handle) Range(fn func(key, val interface{}) bool) {
+	h.rlock()
+	defer h.RUnlock()
+	h.store.each(fn)
+}
+
+func (h *handle) Keys() []interface{} {
+	h.rlock()
+	defer h.RUnlock()
+	return h.store.keys()
+}
+
+// Merge replaces the values of ctx by the values of the given snapshot (see Contexter.Snapshot), keeping the
+// ResponseWriter of ctx. Hooks that have been registered on the snapshot are moved to ctx.
+// It allows to fork the Contexter for a goroutine and take over its changes when it is done.
+func Merge(ctx, snapshot Contexter) {
+	values := map[interface{}]interface{}{}
+	snapshot.Range(func(key, val interface{}) bool {
+		values[key] = val
+		return true
+	})
+
+	ctx.Transaction(func(tc TransactionContexter) {
+		for _, key := range tc.Keys() {
+			if _, has := values[key]; !has && key != responseWriterKey {
+				tc.DelValue(key)
+			}
+		}
+		for key, val := range values {
+			tc.SetValue(key, val)
+		}
+	})
+
+	if h, ok := snapshot.(*handle); ok {
+		h.lock()
+		beforeWriteHeader := append([]func(http.Header, int){}, h.hooks.beforeWriteHeader...)
+		finish := append([]func(){}, h.hooks.finish...)
+		h.hooks.reset()
+		h.Unlock()
+
+		for _, fn := range beforeWriteHeader {
+			ctx.OnBeforeWriteHeader(fn)
+		}
+		for _, fn := range finish {
+			ctx.OnFinish(fn)
+		}
+	}
+}
+
+// context is a Contexter that is smuggled through the middleware stack as http.ResponseWriter.
+// It runs the hooks registered via OnBeforeWriteHeader before the header is written.
+type context struct {
+	http.ResponseWriter // you always need this
+	handle
+}
+
+func (c *context) WriteHeader(code int) {
+	c.runBeforeWriteHeader(c.ResponseWriter.Header(), code)
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *context) Write(b []byte) (int, error) {
+	c.runBeforeWriteHeader(c.ResponseWriter.Header(), http.StatusOK)
+	return c.ResponseWriter.Write(b)
+}
+
+// Flush runs the hooks before writing the header, since flushing writes the header
+func (c *context) Flush() {
+	c.runBeforeWriteHeader(c.ResponseWriter.Header(), http.StatusOK)
+	if fl, ok := c.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// ReadFrom runs the hooks before writing the header and uses the io.ReaderFrom of the underlying
+// http.ResponseWriter if there is one
+func (c *context) ReadFrom(src io.Reader) (int64, error) {
+	c.runBeforeWriteHeader(c.ResponseWriter.Header(), http.StatusOK)
+	if rf, ok := c.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(c.ResponseWriter, src)
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (c *context) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// serve serves the request with a pooled Contexter and runs the hooks registered via OnFinish
+// after next returned, even if it panicked. The http.ResponseWriter that is passed to fn
+// is a Contexter that keeps the optional interfaces of wr.
+func serve(wr http.ResponseWriter, req *http.Request, fn func(w http.ResponseWriter, ctx Contexter, req *http.Request)) {
+	st := acquireStore(wr)
+	defer st.release()
+	c := &context{wr, handle{st, st.gen}}
+	defer c.runFinish()
+	fn(preserve(wr, c, c), &c.handle, req)
 }
 
 type contextHandler struct {
 	http.Handler
 }
 
+// ServeHTTP serves the request with a pooled Contexter that must not be used after ServeHTTP returned
 func (c *contextHandler) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
-	ctx := &context{wr, map[interface{}]Swapper{}, &sync.RWMutex{}}
-	ctx.Set(&ResponseWriter{wr})
-	c.Handler.ServeHTTP(ctx, req)
+	serve(wr, req, c.serve)
+}
+
+func (c *contextHandler) serve(w http.ResponseWriter, ctx Contexter, req *http.Request) {
+	c.Handler.ServeHTTP(w, req)
+}
+
+// contexterKey is the key of the Contexter inside the context.Context of a request
+type contexterKey struct{}
+
+// requestContextHandler passes a Contexter via the context.Context of the request in addition to
+// the http.ResponseWriter, so that it survives the replacement of the http.ResponseWriter
+type requestContextHandler struct {
+	http.Handler
+}
+
+// ServeHTTP serves the request with a pooled Contexter that must not be used after ServeHTTP returned
+func (c *requestContextHandler) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	serve(wr, req, c.serve)
+}
+
+func (c *requestContextHandler) serve(w http.ResponseWriter, ctx Contexter, req *http.Request) {
+	c.Handler.ServeHTTP(w, RequestWithContexter(req, ctx))
+}
+
+// NewContexter returns a new empty Contexter that is not bound to any http.ResponseWriter.
+// It may be passed to RequestWithContexter.
+func NewContexter() Contexter {
+	return &handle{store: &store{data: map[interface{}]interface{}{}}}
+}
+
+// RequestWithContexter returns a shallow copy of the given request that carries the given
+// Contexter inside its context.Context.
+func RequestWithContexter(req *http.Request, ctx Contexter) *http.Request {
+	return req.WithContext(stdcontext.WithValue(req.Context(), contexterKey{}, ctx))
+}
+
+// ContexterFromRequest returns the Contexter that is stored inside the context.Context of the given request.
+// If there is none, ok is false.
+func ContexterFromRequest(req *http.Request) (ctx Contexter, ok bool) {
+	ctx, ok = req.Context().Value(contexterKey{}).(Contexter)
+	return
+}
+
+// contexter returns the Contexter for the given request, looking first inside the context.Context
+// of the request and then at the http.ResponseWriter.
+func contexter(wr http.ResponseWriter, req *http.Request) (ctx Contexter, ok bool) {
+	if req != nil {
+		if ctx, ok = ContexterFromRequest(req); ok {
+			return
+		}
+	}
+	ctx, ok = wr.(Contexter)
+	return
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -81,3 +82,176 @@ func TestContext(t *testing.T) {
 		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
 	}
 }
+
+// foreignWriter replaces the http.ResponseWriter like a third party wrapper would do
+type foreignWriter struct {
+	http.ResponseWriter
+}
+
+func replaceWriter(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	next.ServeHTTP(&foreignWriter{w}, r)
+}
+
+func TestRequestContext(t *testing.T) {
+	var s Stack
+	s.UseWithContext(setCtx("hiho"))
+	s.UseFunc(replaceWriter)
+	s.UseWithContext(appendCtx("-appended"))
+	s.UseFuncWithContext(writeCtxNext)
+
+	rec, req := newTestRequest("GET", "/")
+	s.HandlerWithRequestContext().ServeHTTP(rec, req)
+
+	expected := "hiho-appended"
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+}
+
+func TestRequestWithContexter(t *testing.T) {
+	c := NewContexter()
+	ct := ctx("preloaded")
+	c.Set(&ct)
+
+	rec, req := newTestRequest("GET", "/")
+	ContextHandlerFunc(writeCtx).ServeHTTP(rec, RequestWithContexter(req, c))
+
+	expected := "preloaded"
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+
+	if _, ok := ContexterFromRequest(req); ok {
+		t.Errorf("original request should not carry a Contexter")
+	}
+}
+
+func TestContextReuse(t *testing.T) {
+	var leaked Contexter
+	var s Stack
+	s.UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+		var ct ctx
+		if c.Get(&ct) {
+			t.Errorf("context of previous request must not be visible, got %#v", string(ct))
+		}
+		leaked = c
+		next.ServeHTTP(w, r)
+	})
+	s.UseWithContext(setCtx("hiho"))
+	h := s.WrapFuncWithContext(writeCtx)
+
+	for i := 0; i < 3; i++ {
+		rec, req := newTestRequest("GET", "/")
+		h.ServeHTTP(rec, req)
+		if got, expected := rec.Body.String(), "hiho"; got != expected {
+			t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != errReleased {
+			t.Errorf("using a Contexter after the request should panic with %#v, got %#v", errReleased, r)
+		}
+	}()
+	var ct ctx
+	leaked.Get(&ct)
+}
+
+func TestSnapshot(t *testing.T) {
+	var snap Contexter
+	var s Stack
+	s.UseWithContext(setCtx("hiho"))
+	s.UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+		snap = c.Snapshot()
+		ct := ctx("changed")
+		c.Set(&ct)
+		next.ServeHTTP(w, r)
+	})
+	rec, req := newTestRequest("GET", "/")
+	s.WrapFuncWithContext(writeCtx).ServeHTTP(rec, req)
+
+	if got, expected := rec.Body.String(), "changed"; got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+
+	var ct ctx
+	snap.Get(&ct)
+	if got, expected := string(ct), "hiho"; got != expected {
+		t.Errorf("snapshot value == %#v != %#v", got, expected)
+	}
+
+	var rw ResponseWriter
+	if snap.Get(&rw) {
+		t.Errorf("snapshot must not contain the ResponseWriter")
+	}
+}
+
+func TestRangeAndKeys(t *testing.T) {
+	c := NewContexter()
+	ct := ctx("hiho")
+	c.Set(&ct)
+	c.SetValue("name", "Peter")
+
+	if got := len(c.Keys()); got != 2 {
+		t.Errorf("len(c.Keys()) == %d != 2", got)
+	}
+
+	found := map[interface{}]interface{}{}
+	c.Range(func(key, val interface{}) bool {
+		found[key] = val
+		return true
+	})
+
+	if got := found["name"]; got != "Peter" {
+		t.Errorf("found[\"name\"] == %#v != %#v", got, "Peter")
+	}
+
+	if got := found[reflect.TypeOf(&ct)]; got != &ct {
+		t.Errorf("found[reflect.TypeOf(&ct)] == %#v != %#v", got, &ct)
+	}
+
+	var n int
+	c.Transaction(func(tc TransactionContexter) {
+		tc.Range(func(key, val interface{}) bool {
+			n++
+			return false
+		})
+	})
+
+	if n != 1 {
+		t.Errorf("Range should stop when fn returns false, got %d calls", n)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var finished bool
+	h := New().
+		UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+			c.SetValue("deleted", true)
+			fork := c.Snapshot()
+			fork.DelValue("deleted")
+			ct := ctx("forked")
+			fork.Set(&ct)
+			fork.OnFinish(func() { finished = true })
+			Merge(c, fork)
+			next.ServeHTTP(w, r)
+		}).
+		WrapFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request) {
+			if _, has := c.GetValue("deleted"); has {
+				t.Errorf("value deleted in the snapshot should be deleted")
+			}
+			writeCtx(c, w, r)
+			ReclaimResponseWriter(w) // panics, if the ResponseWriter was removed
+		})
+
+	rec, req := newTestRequest("GET", "/")
+	h.ServeHTTP(rec, req)
+
+	if got, expected := rec.Body.String(), "forked"; got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+
+	if !finished {
+		t.Errorf("OnFinish hook of the snapshot should be run")
+	}
+}
@@ -0,0 +1,238 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Provider may be implemented by middleware that stores values inside the Contexter
+// before calling the next handler.
+type Provider interface {
+	// Provides returns the values the middleware stores: Swappers (compared by type)
+	// or keys like *Key (compared by identity)
+	Provides() []interface{}
+}
+
+// Requirer may be implemented by middleware that needs values inside the Contexter
+// that must have been stored by previous middleware.
+type Requirer interface {
+	// Requires returns the values the middleware needs: Swappers (compared by type)
+	// or keys like *Key (compared by identity)
+	Requires() []interface{}
+}
+
+// DeferredRequirer may be implemented by middleware that needs values inside the Contexter
+// after the next handler returned. Such values may be stored by any middleware of the stack.
+type DeferredRequirer interface {
+	// RequiresAfter returns the values the middleware needs after the next handler returned:
+	// Swappers (compared by type) or keys like *Key (compared by identity)
+	RequiresAfter() []interface{}
+}
+
+// dependencies of a middleware, normalized to the keys that are used inside the Contexter
+type dependencies struct {
+	provides, requires, requiresAfter []interface{}
+}
+
+// depKey returns the key of the given dependency inside the Contexter
+func depKey(dep interface{}) interface{} {
+	if _, isSwapper := dep.(Swapper); isSwapper {
+		return reflect.TypeOf(dep)
+	}
+	return dep
+}
+
+func depKeys(deps []interface{}) []interface{} {
+	keys := make([]interface{}, len(deps))
+	for i, dep := range deps {
+		keys[i] = depKey(dep)
+	}
+	return keys
+}
+
+// dependenciesOf returns the dependencies the given middleware declares
+func dependenciesOf(mw interface{}) (d dependencies) {
+	if c, ok := mw.(*conditional); ok {
+		// the values are not provided for requests that do not match, so only the requirements count
+		d = dependenciesOf(c.mw)
+		d.provides = nil
+		return
+	}
+	if p, ok := mw.(Provider); ok {
+		d.provides = depKeys(p.Provides())
+	}
+	if r, ok := mw.(Requirer); ok {
+		d.requires = depKeys(r.Requires())
+	}
+	if r, ok := mw.(DeferredRequirer); ok {
+		d.requiresAfter = depKeys(r.RequiresAfter())
+	}
+	return
+}
+
+// ErrDependency describes a value that is required by a middleware but not provided by a previous middleware
+type ErrDependency struct {
+	// Middleware describes the middleware that requires the value
+	Middleware Descriptor
+
+	// Required is the name of the type or key that is required
+	Required string
+
+	// Provider describes the middleware that provides the value after the requiring middleware.
+	// It is nil, if no middleware provides the value.
+	Provider *Descriptor
+}
+
+// Error returns the error message
+func (e ErrDependency) Error() string {
+	if e.Provider == nil {
+		return fmt.Sprintf("%s requires %s which is not provided by any middleware", e.Middleware, e.Required)
+	}
+	return fmt.Sprintf("%s requires %s which is provided later by %s", e.Middleware, e.Required, *e.Provider)
+}
+
+// ErrDependencies is the error returned by ValidateWithContext and Validate if declared dependencies of
+// middleware are not satisfied.
+type ErrDependencies []ErrDependency
+
+// Error returns the error messages of all ErrDependency, one per line
+func (e ErrDependencies) Error() string {
+	var buf bytes.Buffer
+	for i, dep := range e {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(dep.Error())
+	}
+	return buf.String()
+}
+
+// depName returns the name of the dependency key for error messages
+func depName(key interface{}) string {
+	if t, ok := key.(reflect.Type); ok {
+		return t.String()
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+// flatten returns the entries of the stack in the order they are called, including the
+// entries of embedded stacks right after the embedding entry.
+// If withContext is true, middleware that returns errors of a stack without OnError stores them inside the Contexter,
+// so it provides the Error and requires ErrorHandling.
+func (s *Stack) flatten(withContext bool) (entries []*entry) {
+	for _, e := range s.ordered() {
+		if withContext && e.withError != nil && s.errorHandler == nil {
+			e = e.storingError()
+		}
+		entries = append(entries, e)
+		if e.sub != nil {
+			sub := e.sub.flatten(withContext)
+			if e.cond != nil {
+				sub = withoutProvides(sub)
+			}
+			entries = append(entries, sub...)
+		}
+	}
+	return
+}
+
+// withoutProvides returns copies of the given entries that provide nothing, since they are only called conditionally
+func withoutProvides(entries []*entry) []*entry {
+	res := make([]*entry, len(entries))
+	for i, e := range entries {
+		cp := *e
+		cp.deps.provides = nil
+		res[i] = &cp
+	}
+	return res
+}
+
+// storingError returns a copy of the entry that additionally provides the Error and requires ErrorHandling
+func (e *entry) storingError() *entry {
+	cp := *e
+	cp.deps.provides = append([]interface{}{depKey(&Error{})}, e.deps.provides...)
+	cp.deps.requires = append([]interface{}{ErrorHandling}, e.deps.requires...)
+	return &cp
+}
+
+// checkDependencies checks the declared dependencies of the middleware and the optional app.
+// If withContext is false, the errors of middleware without OnError are written (see OnError).
+func (s *Stack) checkDependencies(app interface{}, withContext bool) error {
+	entries := s.flatten(withContext)
+	if app != nil {
+		e := &entry{deps: dependenciesOf(app)}
+		e.desc.Kind = KindContextHandler
+		e.desc.Name = nameOf(app)
+		entries = append(entries, e)
+	}
+
+	providers := map[interface{}]*Descriptor{}
+	for _, e := range entries {
+		for _, key := range e.deps.provides {
+			if _, has := providers[key]; !has {
+				providers[key] = &e.desc
+			}
+		}
+	}
+
+	var errs ErrDependencies
+	provided := map[interface{}]bool{}
+	for _, e := range entries {
+		for _, key := range e.deps.requires {
+			if !provided[key] {
+				errs = append(errs, ErrDependency{e.desc, depName(key), providers[key]})
+			}
+		}
+		for _, key := range e.deps.requiresAfter {
+			if providers[key] == nil {
+				errs = append(errs, ErrDependency{e.desc, depName(key), nil})
+			}
+		}
+		for _, key := range e.deps.provides {
+			provided[key] = true
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateWithContext checks if the declared dependencies (see Provider, Requirer and DeferredRequirer)
+// of the middleware are satisfied. Otherwise ErrDependencies is returned.
+//
+// Middleware that returns errors requires ErrorHandling (e.g. a previous mw.ErrorHandler), if the stack has no OnError,
+// since its errors are stored inside the Contexter and would be dropped otherwise.
+func (s *Stack) ValidateWithContext() error {
+	return s.checkDependencies(nil, true)
+}
+
+// MustWrapWithContext is like WrapWithContext but panics if the declared dependencies of the middleware and the app
+// are not satisfied
+func (s *Stack) MustWrapWithContext(app ContextHandler) http.Handler {
+	if err := s.checkDependencies(app, true); err != nil {
+		panic(err)
+	}
+	return s.WrapWithContext(app)
+}
+
+// MustWrapFuncWithContext is like WrapFuncWithContext but panics if the declared dependencies of the middleware
+// are not satisfied
+func (s *Stack) MustWrapFuncWithContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request)) http.Handler {
+	if err := s.ValidateWithContext(); err != nil {
+		panic(err)
+	}
+	return s.WrapFuncWithContext(fn)
+}
+
+// MustHandlerWithContext is like HandlerWithContext but panics if the declared dependencies of the middleware
+// are not satisfied
+func (s *Stack) MustHandlerWithContext() http.Handler {
+	if err := s.ValidateWithContext(); err != nil {
+		panic(err)
+	}
+	return s.HandlerWithContext()
+}
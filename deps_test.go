@@ -0,0 +1,103 @@
+package stack
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type provideCtx struct{ setCtx }
+
+func (provideCtx) Provides() []interface{} { return []interface{}{new(ctx)} }
+
+type requireCtx struct{}
+
+func (requireCtx) ServeHTTP(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	writeCtxNext(c, w, r, next)
+}
+
+func (requireCtx) Requires() []interface{} { return []interface{}{new(ctx)} }
+
+type requireCtxAfter struct{}
+
+func (requireCtxAfter) ServeHTTP(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	next.ServeHTTP(w, r)
+	writeCtx(c, w, r)
+}
+
+func (requireCtxAfter) RequiresAfter() []interface{} { return []interface{}{new(ctx)} }
+
+func TestDependencies(t *testing.T) {
+	ok := []*Stack{
+		New().UseWithContext(provideCtx{"a"}).UseWithContext(requireCtx{}),
+		New().UseWithContext(requireCtxAfter{}).UseWithContext(provideCtx{"a"}),
+		New().UseWrapper(New().UseWithContext(provideCtx{"a"})).UseWithContext(requireCtx{}),
+	}
+
+	for i, s := range ok {
+		if err := s.ValidateWithContext(); err != nil {
+			t.Errorf("stack %d: unexpected error: %s", i, err)
+		}
+	}
+
+	s := New().
+		UseWithContext(requireCtx{}).
+		UseWithContext(provideCtx{"a"})
+
+	err := s.ValidateWithContext()
+	errs, isDeps := err.(ErrDependencies)
+	if !isDeps || len(errs) != 1 {
+		t.Fatalf("expected one ErrDependency, got %#v", err)
+	}
+
+	if errs[0].Provider == nil || errs[0].Provider.Name != "stack.provideCtx" {
+		t.Errorf("wrong provider: %#v", errs[0].Provider)
+	}
+
+	if got, want := errs[0].Required, "*stack.ctx"; got != want {
+		t.Errorf("errs[0].Required == %#v != %#v", got, want)
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "provided later by") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	s = New().UseWithContext(requireCtxAfter{}).UseWithContext(requireCtx{})
+	err = s.ValidateWithContext()
+	if errs, _ := err.(ErrDependencies); len(errs) != 2 || errs[0].Provider != nil {
+		t.Errorf("expected two missing providers, got %#v", err)
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "not provided by any middleware") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	defer func() {
+		if _, ok := recover().(ErrDependencies); !ok {
+			t.Errorf("MustHandlerWithContext should panic with ErrDependencies")
+		}
+	}()
+	s.MustHandlerWithContext()
+}
+
+func TestConditionalDependencies(t *testing.T) {
+	always := MatchFunc(func(*http.Request) bool { return true })
+
+	tests := []struct {
+		stack  *Stack
+		errors int
+	}{
+		{New().UseIf(always, provideCtx{"a"}).UseWithContext(requireCtx{}), 1},
+		{New().UseUnless(always, New().UseWithContext(provideCtx{"a"})).UseWithContext(requireCtx{}), 1},
+		{New().UseIf(always, requireCtx{}), 1},
+		{New().UseWithContext(provideCtx{"a"}).UseIf(always, requireCtx{}), 0},
+		{New().UseWithContext(provideCtx{"a"}).UseIf(always, New().UseWithContext(requireCtx{})), 0},
+	}
+
+	for i, test := range tests {
+		errs, _ := test.stack.ValidateWithContext().(ErrDependencies)
+		if len(errs) != test.errors {
+			t.Errorf("stack %d: expected %d errors, got %v", i, test.errors, errs)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Kind is the kind of a middleware inside a Stack, depending on the UseXXX method that added it
+type Kind int
+
+const (
+	// KindMiddleware is the kind of middleware added via Use and UseFunc
+	KindMiddleware Kind = iota
+
+	// KindContextMiddleware is the kind of middleware added via UseWithContext and UseFuncWithContext
+	KindContextMiddleware
+
+	// KindHandler is the kind of middleware added via UseHandler and UseHandlerFunc
+	KindHandler
+
+	// KindContextHandler is the kind of middleware added via UseHandlerWithContext and UseHandlerFuncWithContext
+	KindContextHandler
+
+	// KindWrapper is the kind of middleware added via UseWrapper and UseWrapperFunc
+	KindWrapper
+
+	// KindMiddlewareWithError is the kind of middleware added via UseWithError and UseFuncWithError
+	KindMiddlewareWithError
+
+	// KindHandlerWithError is the kind of middleware added via UseHandlerWithError and UseHandlerFuncWithError
+	KindHandlerWithError
+)
+
+var kindNames = map[Kind]string{
+	KindMiddleware:          "Middleware",
+	KindContextMiddleware:   "ContextMiddleware",
+	KindHandler:             "Handler",
+	KindContextHandler:      "ContextHandler",
+	KindWrapper:             "Wrapper",
+	KindMiddlewareWithError: "MiddlewareWithError",
+	KindHandlerWithError:    "HandlerWithError",
+}
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	if name, has := kindNames[k]; has {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Descriptor describes a middleware inside a Stack
+type Descriptor struct {
+	// Name is the result of the String method, if the middleware is a fmt.Stringer,
+	// the name of the function for functions and the type otherwise
+	Name string
+
+	// Kind is the kind of the middleware
+	Kind Kind
+
+	// Label is the label that has been given to the middleware via Stack.Label
+	Label string
+
+	// Phase is the phase of the middleware (see Stack.InPhase)
+	Phase Phase
+
+	// Caller is the source location (file:line) where the middleware was added to the stack
+	Caller string
+
+	// Sub describes the embedded stack, if the middleware is a Stack itself
+	Sub []Descriptor
+}
+
+// String returns a single line description of the middleware (without embedded stack)
+func (d Descriptor) String() string {
+	if d.Label != "" {
+		return fmt.Sprintf("%s [%s] %s (%s)", d.Kind, d.Label, d.Name, d.Caller)
+	}
+	return fmt.Sprintf("%s %s (%s)", d.Kind, d.Name, d.Caller)
+}
+
+// nameOf returns the name of the given middleware for the Descriptor
+func nameOf(mw interface{}) string {
+	if _, isStack := mw.(*Stack); isStack {
+		return fmt.Sprintf("%T", mw)
+	}
+	if st, ok := mw.(fmt.Stringer); ok {
+		return st.String()
+	}
+	if v := reflect.ValueOf(mw); v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T", mw)
+}
+
+// Describe returns the descriptors of all middleware inside the stack in the order they
+// are called (see Phase). Embedded stacks are described via the Sub field of their Descriptor.
+func (s *Stack) Describe() []Descriptor {
+	entries := s.ordered()
+	descs := make([]Descriptor, len(entries))
+	for i, e := range entries {
+		descs[i] = e.desc
+		if e.sub != nil {
+			descs[i].Sub = e.sub.Describe()
+		}
+	}
+	return descs
+}
+
+// String renders the full middleware chain as a tree with one middleware per line.
+// Middleware of embedded stacks is indented below the embedding stack.
+func (s *Stack) String() string {
+	var buf bytes.Buffer
+	writeDescriptors(&buf, s.Describe(), 0)
+	return buf.String()
+}
+
+func writeDescriptors(buf *bytes.Buffer, descs []Descriptor, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, d := range descs {
+		buf.WriteString(indent)
+		buf.WriteString(d.String())
+		buf.WriteString("\n")
+		writeDescriptors(buf, d.Sub, depth+1)
+	}
+}
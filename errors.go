@@ -0,0 +1,205 @@
+package stack
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is a Swapper that saves an error inside a Contexter
+type Error struct {
+	Err error
+}
+
+// Swap implements the Swapper interface.
+func (e *Error) Swap(repl interface{}) {
+	*e = *(repl.(*Error))
+}
+
+// ErrorHandling is the dependency key of middleware that handles the errors that are stored via SetError.
+// It is provided by mw.ErrorHandler and required by middleware that stores errors (see Provider and Requirer),
+// so that ValidateWithContext reports stored errors that would never be handled.
+var ErrorHandling = errorHandling{}
+
+type errorHandling struct{}
+
+func (errorHandling) String() string { return "error handling" }
+
+// SetError stores the given error inside the given Contexter if err is not nil
+func SetError(err error, ctx Contexter) {
+	if err == nil {
+		return
+	}
+	ctx.Set(&Error{err})
+}
+
+// GetError gets the error out of the given Contexter.
+// If there is no error, nil is returned.
+func GetError(ctx Contexter) error {
+	var e Error
+	if ctx.Get(&e) {
+		return e.Err
+	}
+	return nil
+}
+
+// HTTPError is an error that carries the HTTP status code of the response
+type HTTPError struct {
+	// Code is the HTTP status code
+	Code int
+
+	// Err is the underlying error, might be nil
+	Err error
+}
+
+// NewHTTPError returns a new HTTPError for the given status code and error
+func NewHTTPError(code int, err error) *HTTPError {
+	return &HTTPError{Code: code, Err: err}
+}
+
+// Error returns the message of the underlying error or the status text if there is none
+func (h *HTTPError) Error() string {
+	if h.Err == nil {
+		return http.StatusText(h.Code)
+	}
+	return h.Err.Error()
+}
+
+// StatusCode returns the HTTP status code
+func (h *HTTPError) StatusCode() int {
+	return h.Code
+}
+
+// Unwrap returns the underlying error
+func (h *HTTPError) Unwrap() error {
+	return h.Err
+}
+
+// StatusCoder is an error that carries a HTTP status code, like HTTPError
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// StatusCode returns the HTTP status code of the first StatusCoder inside the chain of the given error
+// (see errors.As) and http.StatusInternalServerError if there is none
+func StatusCode(err error) int {
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteError writes the status code of the given error (see StatusCode) and its status text
+// to the http.ResponseWriter. The error message is not written, since it might expose internals.
+func WriteError(err error, wr http.ResponseWriter, req *http.Request) {
+	code := StatusCode(err)
+	http.Error(wr, http.StatusText(code), code)
+}
+
+// OnError sets the function that handles the errors returned by middleware and handlers that were added via
+// UseWithError, UseFuncWithError, UseHandlerWithError, UseHandlerFuncWithError and by the app of WrapWithError and WrapFuncWithError.
+// The function might be mw.ErrorHandler or WriteError.
+//
+// Without such a function, the error is stored inside the Contexter via SetError (to be handled by e.g. mw.ErrorHandler) or
+// written via WriteError, if there is no Contexter. ValidateWithContext reports such stacks without ErrorHandling.
+// Embedded stacks do not inherit the function.
+// The function is only respected by handlers that are created afterwards via Wrap, Handler, WrapWithContext etc.
+func (s *Stack) OnError(fn func(error, http.ResponseWriter, *http.Request)) *Stack {
+	s.errorHandler = fn
+	return s
+}
+
+// onError returns the function that handles the errors of the stack
+func (s *Stack) onError() func(error, http.ResponseWriter, *http.Request) {
+	if s.errorHandler != nil {
+		return s.errorHandler
+	}
+	return setOrWriteError
+}
+
+// setOrWriteError stores the error inside the Contexter or writes it, if there is no Contexter
+func setOrWriteError(err error, wr http.ResponseWriter, req *http.Request) {
+	if ctx, ok := contexter(wr, req); ok {
+		SetError(err, ctx)
+		return
+	}
+	WriteError(err, wr, req)
+}
+
+// withError returns the wrapping function for a middleware that returns an error
+// when the error handler of the stack is known
+type withError func(onError func(error, http.ResponseWriter, *http.Request)) func(http.Handler) http.Handler
+
+// mwWithError returns the wrapping function for a middleware that returns an error
+func mwWithError(fn func(wr http.ResponseWriter, req *http.Request, next http.Handler) error) withError {
+	return func(onError func(error, http.ResponseWriter, *http.Request)) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+				if err := fn(wr, req, next); err != nil {
+					onError(err, wr, req)
+				}
+			})
+		}
+	}
+}
+
+// handlerWithError returns the wrapping function for a handler that returns an error.
+// The next handler is only called if there was no error.
+func handlerWithError(fn func(wr http.ResponseWriter, req *http.Request) error) withError {
+	return func(onError func(error, http.ResponseWriter, *http.Request)) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+				if err := fn(wr, req); err != nil {
+					onError(err, wr, req)
+					return
+				}
+				next.ServeHTTP(wr, req)
+			})
+		}
+	}
+}
+
+// setWithError sets the wrapping function of the middleware that has been added last
+func (s *Stack) setWithError(w withError) *Stack {
+	s.entries[len(s.entries)-1].withError = w
+	return s
+}
+
+// UseWithError adds the given middleware that returns an error to the stack.
+// The error is handled as described for OnError.
+func (s *Stack) UseWithError(mw MiddlewareWithError) *Stack {
+	return s.push(KindMiddlewareWithError, mw, nil).setWithError(mwWithError(mw.ServeHTTP))
+}
+
+// UseFuncWithError is like UseWithError for a function
+func (s *Stack) UseFuncWithError(fn func(wr http.ResponseWriter, req *http.Request, next http.Handler) error) *Stack {
+	return s.push(KindMiddlewareWithError, fn, nil).setWithError(mwWithError(fn))
+}
+
+// UseHandlerWithError adds the given handler that returns an error as middleware to the stack.
+// The handler will be called before the next middleware. If it returns an error, the error
+// is handled as described for OnError and the next middleware is not called.
+func (s *Stack) UseHandlerWithError(mw HandlerWithError) *Stack {
+	return s.push(KindHandlerWithError, mw, nil).setWithError(handlerWithError(mw.ServeHTTP))
+}
+
+// UseHandlerFuncWithError is like UseHandlerWithError for a function
+func (s *Stack) UseHandlerFuncWithError(fn func(wr http.ResponseWriter, req *http.Request) error) *Stack {
+	return s.push(KindHandlerWithError, fn, nil).setWithError(handlerWithError(fn))
+}
+
+// WrapWithError wraps the stack around the given app that returns an error.
+// The error is handled as described for OnError.
+func (s *Stack) WrapWithError(app HandlerWithError) http.Handler {
+	return s.WrapFuncWithError(app.ServeHTTP)
+}
+
+// WrapFuncWithError is like WrapWithError for a function
+func (s *Stack) WrapFuncWithError(fn func(wr http.ResponseWriter, req *http.Request) error) http.Handler {
+	onError := s.onError()
+	return s.Wrap(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		if err := fn(wr, req); err != nil {
+			onError(err, wr, req)
+		}
+	}))
+}
@@ -0,0 +1,155 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var errNotAllowed = NewHTTPError(http.StatusForbidden, errors.New("not allowed"))
+
+func guardWithError(w http.ResponseWriter, r *http.Request, next http.Handler) error {
+	if r.URL.Query().Get("user") == "" {
+		return fmt.Errorf("guard: %w", errNotAllowed)
+	}
+	next.ServeHTTP(w, r)
+	return nil
+}
+
+func appWithError(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Query().Get("fail") != "" {
+		return errors.New("failed")
+	}
+	w.Write([]byte("ok"))
+	return nil
+}
+
+func TestErrorsWithHandler(t *testing.T) {
+	var handled error
+	h := New().
+		OnError(func(err error, w http.ResponseWriter, r *http.Request) {
+			handled = err
+			WriteError(err, w, r)
+		}).
+		UseFuncWithError(guardWithError).
+		WrapFuncWithError(appWithError)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+		err  error
+	}{
+		{"/", http.StatusForbidden, "Forbidden\n", errNotAllowed},
+		{"/?user=a&fail=1", http.StatusInternalServerError, "Internal Server Error\n", nil},
+		{"/?user=a", http.StatusOK, "ok", nil},
+	}
+
+	for _, test := range tests {
+		handled = nil
+		rec, req := newTestRequest("GET", test.path)
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != test.code {
+			t.Errorf("GET %s: rec.Code == %d != %d", test.path, rec.Code, test.code)
+		}
+
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("GET %s: rec.Body.String() == %#v != %#v", test.path, got, test.body)
+		}
+
+		if test.err != nil && !errors.Is(handled, test.err) {
+			t.Errorf("GET %s: handled error %v is not %v", test.path, handled, test.err)
+		}
+	}
+}
+
+func TestErrorsInContext(t *testing.T) {
+	var stored error
+	h := New().
+		UseFuncWithContext(func(ctx Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+			next.ServeHTTP(w, r)
+			stored = GetError(ctx)
+		}).
+		UseHandlerFuncWithError(func(w http.ResponseWriter, r *http.Request) error {
+			return errNotAllowed
+		}).
+		WrapFuncWithContext(writeCtx)
+
+	rec, req := newTestRequest("GET", "/")
+	h.ServeHTTP(rec, req)
+
+	if stored != errNotAllowed {
+		t.Errorf("stored error == %v != %v", stored, errNotAllowed)
+	}
+
+	if got := rec.Body.String(); got != "" {
+		t.Errorf("next handler should not be called after an error, body is %#v", got)
+	}
+
+	if got := StatusCode(stored); got != http.StatusForbidden {
+		t.Errorf("StatusCode(stored) == %d != %d", got, http.StatusForbidden)
+	}
+}
+
+type handleErrors struct{}
+
+func (handleErrors) ServeHTTP(ctx Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	next.ServeHTTP(w, r)
+	if err := GetError(ctx); err != nil {
+		WriteError(err, w, r)
+	}
+}
+
+func (handleErrors) Provides() []interface{} { return []interface{}{ErrorHandling} }
+
+func TestErrorsValidate(t *testing.T) {
+	s := New().UseFuncWithError(guardWithError)
+
+	errs, _ := s.ValidateWithContext().(ErrDependencies)
+	if len(errs) != 1 || errs[0].Required != "error handling" {
+		t.Errorf("expected missing error handling, got %#v", errs)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("errors are written without Contexter, got %s", err)
+	}
+
+	ok := []*Stack{
+		New().OnError(WriteError).UseFuncWithError(guardWithError),
+		New().UseWithContext(handleErrors{}).UseFuncWithError(guardWithError),
+		New().UseWithContext(handleErrors{}).UseWrapper(New().UseFuncWithError(guardWithError)),
+	}
+
+	for i, s := range ok {
+		if err := s.ValidateWithContext(); err != nil {
+			t.Errorf("stack %d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestOnErrorCapturedOnWrap(t *testing.T) {
+	var handled []string
+	onError := func(name string) func(error, http.ResponseWriter, *http.Request) {
+		return func(err error, w http.ResponseWriter, r *http.Request) {
+			handled = append(handled, name)
+		}
+	}
+
+	s := New().OnError(onError("first")).UseFuncWithError(guardWithError)
+	h := s.WrapFunc(writeString("ok").ServeHTTP)
+	s.OnError(onError("second"))
+
+	other := New().OnError(onError("other")).UseFuncWithError(guardWithError)
+	concat := New().OnError(onError("concat")).Concat(other)
+
+	for _, h := range []http.Handler{h, s.WrapFunc(writeString("ok").ServeHTTP), concat.WrapFunc(writeString("ok").ServeHTTP)} {
+		rec, req := newTestRequest("GET", "/")
+		h.ServeHTTP(rec, req)
+	}
+
+	if got, want := fmt.Sprint(handled), "[first second concat]"; got != want {
+		t.Errorf("handled by %s, expected %s", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package stack
+
+import "net/http"
+
+// Frozen is an immutable, compiled stack that is created by Stack.Freeze.
+// Later changes to the original stack (and to its embedded stacks) do not affect it,
+// so it can be shared across goroutines and mounted at several places.
+//
+// The handlers without app (Handler, HandlerWithContext, HandlerWithRequestContext) are
+// compiled once by Freeze. The WrapXXX methods compile the chain around the given app for each call.
+type Frozen struct {
+	stack   *Stack
+	handler http.Handler
+}
+
+// Freeze returns an immutable, compiled copy of the stack. Embedded stacks are frozen as well,
+// including the stacks that are embedded via UseIf or UseUnless.
+func (s *Stack) Freeze() *Frozen {
+	st := s.copy()
+	return &Frozen{stack: st, handler: st.wrap(nil)}
+}
+
+// copy returns a deep copy of the stack that is not affected by changes to the stack or
+// its embedded stacks
+func (s *Stack) copy() *Stack {
+	st := &Stack{
+		entries:      make([]*entry, len(s.entries)),
+		instrumenter: s.instrumenter,
+		errorHandler: s.errorHandler,
+	}
+	for i, e := range s.entries {
+		cp := *e
+		switch {
+		case cp.embedded:
+			cp.sub = cp.sub.copy()
+			cp.fn = cp.sub.Wrap
+		case cp.cond != nil && cp.sub != nil:
+			cp.sub = cp.sub.copy()
+			cp.cond, _ = newConditional(cp.cond.m, cp.sub, cp.cond.negate)
+			cp.fn = cp.cond.Wrap
+		}
+		st.entries[i] = &cp
+	}
+	return st
+}
+
+// Thaw returns a mutable copy of the frozen stack, e.g. to derive a new stack from it
+func (f *Frozen) Thaw() *Stack {
+	return f.stack.copy()
+}
+
+// ServeHTTP serves the request via the compiled Handler
+func (f *Frozen) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	f.handler.ServeHTTP(wr, req)
+}
+
+// Handler returns the compiled handler of the stack
+func (f *Frozen) Handler() http.Handler {
+	return f.handler
+}
+
+// HandlerWithContext is like Stack.HandlerWithContext but returns the compiled handler
+func (f *Frozen) HandlerWithContext() http.Handler {
+	return &contextHandler{f.handler}
+}
+
+// HandlerWithRequestContext is like Stack.HandlerWithRequestContext but returns the compiled handler
+func (f *Frozen) HandlerWithRequestContext() http.Handler {
+	return &requestContextHandler{f.handler}
+}
+
+// Wrap wraps the frozen stack around the next handler. It implements the Wrapper interface, so that
+// a frozen stack may be embedded via UseWrapper.
+func (f *Frozen) Wrap(next http.Handler) http.Handler {
+	return f.stack.Wrap(next)
+}
+
+// WrapFunc is like Stack.WrapFunc
+func (f *Frozen) WrapFunc(fn func(wr http.ResponseWriter, req *http.Request)) http.Handler {
+	return f.stack.WrapFunc(fn)
+}
+
+// WrapWithContext is like Stack.WrapWithContext
+func (f *Frozen) WrapWithContext(app ContextHandler) http.Handler {
+	return f.stack.WrapWithContext(app)
+}
+
+// WrapFuncWithContext is like Stack.WrapFuncWithContext
+func (f *Frozen) WrapFuncWithContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request)) http.Handler {
+	return f.stack.WrapFuncWithContext(fn)
+}
+
+// WrapWithRequestContext is like Stack.WrapWithRequestContext
+func (f *Frozen) WrapWithRequestContext(app ContextHandler) http.Handler {
+	return f.stack.WrapWithRequestContext(app)
+}
+
+// WrapFuncWithRequestContext is like Stack.WrapFuncWithRequestContext
+func (f *Frozen) WrapFuncWithRequestContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request)) http.Handler {
+	return f.stack.WrapFuncWithRequestContext(fn)
+}
+
+// WrapWithError is like Stack.WrapWithError
+func (f *Frozen) WrapWithError(app HandlerWithError) http.Handler {
+	return f.stack.WrapWithError(app)
+}
+
+// WrapFuncWithError is like Stack.WrapFuncWithError
+func (f *Frozen) WrapFuncWithError(fn func(wr http.ResponseWriter, req *http.Request) error) http.Handler {
+	return f.stack.WrapFuncWithError(fn)
+}
+
+// Describe is like Stack.Describe
+func (f *Frozen) Describe() []Descriptor {
+	return f.stack.Describe()
+}
+
+// String is like Stack.String
+func (f *Frozen) String() string {
+	return f.stack.String()
+}
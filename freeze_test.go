@@ -0,0 +1,81 @@
+package stack
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestConcatDoesNotShare(t *testing.T) {
+	base := New().UseWrapper(writeString("a"))
+	base.UseWrapper(writeString("b")) // leaves spare capacity in the backing array
+
+	x := base.Concat(New().UseWrapper(writeString("x")))
+	y := base.Concat(New().UseWrapper(writeString("y")))
+	x.Label("last")
+
+	for s, expected := range map[*Stack]string{x: "abx", y: "aby"} {
+		rec, req := newTestRequest("GET", "/")
+		s.Handler().ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != expected {
+			t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+		}
+	}
+
+	if y.indexOf("last") != -1 || base.indexOf("last") != -1 {
+		t.Errorf("labeling a concatenated stack should not affect other stacks")
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	inner := New().UseWrapper(writeString("i"))
+	s := New().UseWrapper(writeString("a")).UseWrapper(inner)
+	f := s.Freeze()
+
+	s.UseWrapper(writeString("b"))
+	inner.UseWrapper(writeString("j"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec, req := newTestRequest("GET", "/")
+			f.ServeHTTP(rec, req)
+			if got, expected := rec.Body.String(), "ai"; got != expected {
+				t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+			}
+		}()
+	}
+	wg.Wait()
+
+	rec, req := newTestRequest("GET", "/")
+	f.Thaw().UseWrapper(writeString("c")).WrapFunc(writeString("!").ServeHTTP).ServeHTTP(rec, req)
+	if got, expected := rec.Body.String(), "aic!"; got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+}
+
+func TestFreezeConditional(t *testing.T) {
+	inner := New().UseWrapper(writeString("i"))
+	outer := New().UseWrapper(writeString("o"))
+	s := New().
+		UseIf(MatchFunc(func(*http.Request) bool { return true }), inner).
+		UseUnless(MatchFunc(func(*http.Request) bool { return false }), outer)
+	f := s.Freeze()
+
+	inner.UseWrapper(writeString("j"))
+	outer.UseWrapper(writeString("p"))
+
+	rec, req := newTestRequest("GET", "/")
+	f.ServeHTTP(rec, req)
+	if got, expected := rec.Body.String(), "io"; got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+
+	rec, req = newTestRequest("GET", "/")
+	s.Handler().ServeHTTP(rec, req)
+	if got, expected := rec.Body.String(), "ijop"; got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build ignore
+// +build ignore
+
+// gen_preserve generates preserve_gen.go, run it via go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// optional are the optional interfaces in the order of their bits inside the mask
+var optional = []struct{ field, value string }{
+	{"Contexter", "ctx"},
+	{"http.Flusher", "fl"},
+	{"http.Hijacker", "hj"},
+	{"http.Pusher", "ps"},
+	{"http.CloseNotifier", "cn"},
+	{"io.ReaderFrom", "rf"},
+}
+
+func main() {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen_preserve.go; DO NOT EDIT.
+
+package stack
+
+import (
+	"io"
+	"net/http"
+)
+
+// combine returns a http.ResponseWriter that embeds base and the optional interfaces that are set in mask
+func combine(mask int, b base, ctx Contexter, fl http.Flusher, hj http.Hijacker, ps http.Pusher, cn http.CloseNotifier, rf io.ReaderFrom) http.ResponseWriter {
+	switch mask {
+`)
+	for mask := 0; mask < 1<<uint(len(optional)); mask++ {
+		fields := []string{"base"}
+		values := []string{"b"}
+		for i, o := range optional {
+			if mask&(1<<uint(i)) != 0 {
+				fields = append(fields, o.field)
+				values = append(values, o.value)
+			}
+		}
+		fmt.Fprintf(&buf, "\tcase %d:\n\t\treturn struct {\n\t\t\t%s\n\t\t}{%s}\n", mask, strings.Join(fields, "\n\t\t\t"), strings.Join(values, ", "))
+	}
+	buf.WriteString("\tdefault:\n\t\tpanic(\"unreachable\")\n\t}\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("preserve_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -28,12 +28,14 @@ func (c ctxHandlerFunc) ServeHTTP(ctx Contexter, wr http.ResponseWriter, req *ht
 	c(ctx, wr, req)
 }
 
+const errNoContexter = "stack.Contexter neither in ResponseWriter nor in request context (use WrapWithContext or WrapWithRequestContext)"
+
 type ContextHandlerFunc func(ctx Contexter, wr http.ResponseWriter, req *http.Request)
 
 func (c ContextHandlerFunc) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
-	ctx, ok := wr.(Contexter)
+	ctx, ok := contexter(wr, req)
 	if !ok {
-		panic("wrack.Context not in stack")
+		panic(errNoContexter)
 	}
 	c(ctx, wr, req)
 }
@@ -43,7 +45,10 @@ type mwCtxHandlerFunc func(ctx Contexter, wr http.ResponseWriter, req *http.Requ
 func (m mwCtxHandlerFunc) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
-			ctx := wr.(Contexter)
+			ctx, ok := contexter(wr, req)
+			if !ok {
+				panic(errNoContexter)
+			}
 			m(ctx, wr, req, next)
 		})
 	}
@@ -0,0 +1,112 @@
+package stack
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// HookPanic describes a panic inside a hook that was registered via
+// Contexter.OnBeforeWriteHeader or Contexter.OnFinish
+type HookPanic struct {
+	// Hook is the name of the registration method, i.e. "OnBeforeWriteHeader" or "OnFinish"
+	Hook string
+
+	// Value is the recovered value
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (h HookPanic) Error() string {
+	return fmt.Sprintf("panic in %s hook: %v\n%s", h.Hook, h.Value, h.Stack)
+}
+
+// ReportHookPanic is called for each panic inside a hook. The panic does not propagate,
+// the remaining hooks are run anyway.
+// The default logs via the log package. It might be replaced at program start.
+var ReportHookPanic = func(p HookPanic) {
+	log.Print(p.Error())
+}
+
+// hooks are the callbacks that are registered for a request
+type hooks struct {
+	beforeWriteHeader []func(header http.Header, code int)
+	finish            []func()
+
+	// wroteHeader is 1 after the hooks before writing the header have been run.
+	// It is read atomically, so that writes after the header do not need the lock.
+	wroteHeader uint32
+}
+
+// reset removes all hooks while keeping the allocated slices
+func (h *hooks) reset() {
+	for i := range h.beforeWriteHeader {
+		h.beforeWriteHeader[i] = nil
+	}
+	h.beforeWriteHeader = h.beforeWriteHeader[:0]
+	h.finish = h.finish[:0]
+	atomic.StoreUint32(&h.wroteHeader, 0)
+}
+
+func (h *handle) OnBeforeWriteHeader(fn func(header http.Header, code int)) {
+	h.lock()
+	defer h.Unlock()
+	h.hooks.beforeWriteHeader = append(h.hooks.beforeWriteHeader, fn)
+}
+
+func (h *handle) OnFinish(fn func()) {
+	h.lock()
+	defer h.Unlock()
+	h.hooks.finish = append(h.hooks.finish, fn)
+}
+
+// runBeforeWriteHeader runs the hooks before writing the header, if they did not run yet.
+// It must be called by the goroutine that writes the response.
+func (h *handle) runBeforeWriteHeader(header http.Header, code int) {
+	if atomic.LoadUint32(&h.hooks.wroteHeader) == 1 {
+		return
+	}
+	h.lock()
+	if atomic.LoadUint32(&h.hooks.wroteHeader) == 1 {
+		h.Unlock()
+		return
+	}
+	atomic.StoreUint32(&h.hooks.wroteHeader, 1)
+	fns := h.hooks.beforeWriteHeader
+	h.Unlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		fn := fns[i]
+		runHook("OnBeforeWriteHeader", func() { fn(header, code) })
+	}
+}
+
+// runFinish runs the hooks registered via OnFinish, including the ones that are registered while running
+func (h *handle) runFinish() {
+	for {
+		h.lock()
+		n := len(h.hooks.finish)
+		if n == 0 {
+			h.Unlock()
+			return
+		}
+		fn := h.hooks.finish[n-1]
+		h.hooks.finish[n-1] = nil
+		h.hooks.finish = h.hooks.finish[:n-1]
+		h.Unlock()
+		runHook("OnFinish", fn)
+	}
+}
+
+func runHook(name string, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			ReportHookPanic(HookPanic{Hook: name, Value: p, Stack: debug.Stack()})
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,112 @@
+package stack
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+
+	reported := ReportHookPanic
+	defer func() { ReportHookPanic = reported }()
+	ReportHookPanic = func(p HookPanic) {
+		events = append(events, "panic "+p.Hook)
+	}
+
+	mkStack := func(replace bool) *Stack {
+		s := New().
+			UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+				c.OnFinish(func() { events = append(events, "finish outer") })
+				c.OnBeforeWriteHeader(func(h http.Header, code int) {
+					events = append(events, "header outer")
+					h.Set("X-Outer", "yes")
+				})
+				next.ServeHTTP(w, r)
+			})
+		if replace {
+			s.UseFunc(replaceWriter)
+		}
+		return s.UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+			c.OnFinish(func() { panic("boom") })
+			c.OnFinish(func() { events = append(events, "finish inner") })
+			c.OnBeforeWriteHeader(func(h http.Header, code int) {
+				events = append(events, "header inner")
+				if code != http.StatusCreated {
+					t.Errorf("code == %d != %d", code, http.StatusCreated)
+				}
+			})
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	writeCreated := func(c Contexter, w http.ResponseWriter, r *http.Request) {
+		events = append(events, "app")
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	for _, h := range []http.Handler{
+		mkStack(false).WrapFuncWithContext(writeCreated),
+		mkStack(true).WrapFuncWithRequestContext(writeCreated),
+	} {
+		events = nil
+		rec, req := newTestRequest("GET", "/")
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("X-Outer"); got != "yes" {
+			t.Errorf("header X-Outer == %#v != %#v", got, "yes")
+		}
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("rec.Code == %d != %d", rec.Code, http.StatusCreated)
+		}
+
+		expected := "app,header inner,header outer,finish inner,panic OnFinish,finish outer"
+		if got := strings.Join(events, ","); got != expected {
+			t.Errorf("events == %#v != %#v", got, expected)
+		}
+	}
+}
+
+func TestOnFinishAfterPanic(t *testing.T) {
+	var finished bool
+	h := New().
+		UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+			c.OnFinish(func() { finished = true })
+			panic("boom")
+		}).
+		WrapFuncWithContext(writeCtx)
+
+	func() {
+		defer func() { recover() }()
+		rec, req := newTestRequest("GET", "/")
+		h.ServeHTTP(rec, req)
+	}()
+
+	if !finished {
+		t.Errorf("OnFinish hook should run if the stack panics")
+	}
+}
+
+func TestBeforeWriteHeaderConcurrent(t *testing.T) {
+	ctx := NewContexter().(*handle)
+	var calls int32
+	ctx.OnBeforeWriteHeader(func(http.Header, int) { atomic.AddInt32(&calls, 1) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx.runBeforeWriteHeader(http.Header{}, http.StatusOK)
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("hook called %d times, expected once", calls)
+	}
+}
@@ -0,0 +1,188 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Instrumenter is notified when a request enters and leaves a middleware of a stack.
+// It can be used to emit spans or record metrics per middleware.
+// An Instrumenter must be safe for concurrent use.
+type Instrumenter interface {
+	// Enter is called before the middleware described by d serves the request
+	Enter(d *Descriptor, req *http.Request)
+
+	// Exit is called after the middleware described by d has served the request (also if it panicked).
+	// status is the status code of the response when leaving the middleware (200 for writes without status)
+	// or 0 if nothing was written yet. elapsed is the time spent inside the middleware including all
+	// middleware and handlers that were called by it.
+	Exit(d *Descriptor, req *http.Request, status int, elapsed time.Duration)
+}
+
+// Instrument sets the Instrumenter that is notified for every middleware of the stack.
+// The Instrumenter is only respected by handlers that are created afterwards via Wrap, Handler,
+// WrapWithContext etc. If no Instrumenter is set, there is no overhead when serving.
+// Stacks that are embedded via UseWrapper (also frozen ones) are instrumented as well, by their own Instrumenter
+// if they have one. Stacks that are embedded via UseIf or UseUnless are only instrumented by their own Instrumenter.
+func (s *Stack) Instrument(in Instrumenter) *Stack {
+	s.instrumenter = in
+	return s
+}
+
+// instrumented is a http.Handler of a middleware that notifies an Instrumenter
+type instrumented struct {
+	http.Handler
+	desc *Descriptor
+	in   Instrumenter
+}
+
+func (i *instrumented) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	var sw *statusWriter
+	w := wr
+
+	// reuse the statusWriter of the previous middleware if the http.ResponseWriter was not replaced
+	if u, ok := wr.(Unwrapper); ok {
+		sw, _ = u.Unwrap().(*statusWriter)
+	}
+
+	if sw == nil {
+		sw = &statusWriter{ResponseWriter: wr}
+		w = WrapResponseWriter(wr, sw)
+	}
+
+	i.in.Enter(i.desc, req)
+	start := time.Now()
+	defer func() {
+		i.in.Exit(i.desc, req, sw.status, time.Since(start))
+	}()
+	i.Handler.ServeHTTP(w, req)
+}
+
+// statusWriter tracks the status code of the response that is written to the underlying http.ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// LatencyRecorder is an Instrumenter that aggregates the latencies of each middleware in memory.
+// For each middleware the latest samples are kept to calculate percentiles.
+type LatencyRecorder struct {
+	mx      sync.Mutex
+	size    int
+	order   []*Descriptor
+	samples map[*Descriptor]*latencies
+}
+
+// latencies is a ring buffer of latency samples
+type latencies struct {
+	count   int
+	max     time.Duration
+	samples []time.Duration
+}
+
+var _ Instrumenter = &LatencyRecorder{}
+
+// NewLatencyRecorder returns a new LatencyRecorder that keeps the latest size samples per middleware.
+// If size is not positive, 1000 samples are kept.
+func NewLatencyRecorder(size int) *LatencyRecorder {
+	if size <= 0 {
+		size = 1000
+	}
+	return &LatencyRecorder{size: size, samples: map[*Descriptor]*latencies{}}
+}
+
+// Enter does nothing
+func (l *LatencyRecorder) Enter(d *Descriptor, req *http.Request) {}
+
+// Exit records the elapsed time for the middleware described by d
+func (l *LatencyRecorder) Exit(d *Descriptor, req *http.Request, status int, elapsed time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	lt, has := l.samples[d]
+	if !has {
+		lt = &latencies{samples: make([]time.Duration, 0, l.size)}
+		l.samples[d] = lt
+		l.order = append(l.order, d)
+	}
+	if len(lt.samples) < l.size {
+		lt.samples = append(lt.samples, elapsed)
+	} else {
+		lt.samples[lt.count%l.size] = elapsed
+	}
+	lt.count++
+	if elapsed > lt.max {
+		lt.max = elapsed
+	}
+}
+
+// Reset removes all recorded samples
+func (l *LatencyRecorder) Reset() {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.order = nil
+	l.samples = map[*Descriptor]*latencies{}
+}
+
+// percentile returns the percentile p (between 0 and 1) of the sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// WriteTo writes one line per recorded middleware in the order they were first recorded.
+// Each line has the description of the middleware, the number of requests, the 50th, 90th
+// and 99th percentile and the maximum of the latencies.
+func (l *LatencyRecorder) WriteTo(w io.Writer) (int64, error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	var buf bytes.Buffer
+	for _, d := range l.order {
+		lt := l.samples[d]
+		sorted := make([]time.Duration, len(lt.samples))
+		copy(sorted, lt.samples)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+		fmt.Fprintf(&buf, "%s\tn=%d p50=%s p90=%s p99=%s max=%s\n",
+			d, lt.count, percentile(sorted, 0.5), percentile(sorted, 0.9), percentile(sorted, 0.99), lt.max)
+	}
+	return buf.WriteTo(w)
+}
+
+// String returns what WriteTo would write
+func (l *LatencyRecorder) String() string {
+	var buf bytes.Buffer
+	l.WriteTo(&buf)
+	return buf.String()
+}
@@ -0,0 +1,117 @@
+package stack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type eventRecorder struct {
+	mx     sync.Mutex
+	events []string
+}
+
+func (e *eventRecorder) Enter(d *Descriptor, req *http.Request) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.events = append(e.events, "enter "+d.Label)
+}
+
+func (e *eventRecorder) Exit(d *Descriptor, req *http.Request, status int, elapsed time.Duration) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.events = append(e.events, fmt.Sprintf("exit %s %d", d.Label, status))
+}
+
+func notFound(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func created(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	w.WriteHeader(http.StatusCreated)
+	next.ServeHTTP(w, r)
+}
+
+func TestInstrument(t *testing.T) {
+	var ev eventRecorder
+	s := New().
+		UseWithContext(setCtx("a")).Label("a").
+		UseFunc(replaceWriter).Label("b").
+		UseFunc(created).Label("c").
+		UseFuncWithContext(writeCtxNext).Label("d").
+		Instrument(&ev)
+
+	rec, req := newTestRequest("GET", "/")
+	s.HandlerWithRequestContext().ServeHTTP(rec, req)
+	assertResponse(t, rec, "a", 201)
+
+	expected := []string{
+		"enter a", "enter b", "enter c", "enter d",
+		"exit d 201", "exit c 201", "exit b 201", "exit a 201",
+	}
+
+	if got, want := strings.Join(ev.events, ","), strings.Join(expected, ","); got != want {
+		t.Errorf("events: %#v != %#v", got, want)
+	}
+}
+
+func TestInstrumentEmbedded(t *testing.T) {
+	var ev, own eventRecorder
+	inner := New().UseFunc(created).Label("inner")
+	frozen := New().UseFunc(created).Label("frozen").Freeze()
+	instrumented := New().UseFunc(created).Label("own").Instrument(&own)
+	s := New().
+		UseWrapper(inner).Label("a").
+		UseWrapper(frozen).Label("b").
+		UseWrapper(instrumented).Label("c").
+		Instrument(&ev)
+
+	rec, req := newTestRequest("GET", "/")
+	s.Handler().ServeHTTP(rec, req)
+
+	expected := []string{
+		"enter a", "enter inner", "enter b", "enter frozen", "enter c",
+		"exit c 201", "exit frozen 201", "exit b 201", "exit inner 201", "exit a 201",
+	}
+
+	if got, want := strings.Join(ev.events, ","), strings.Join(expected, ","); got != want {
+		t.Errorf("events: %#v != %#v", got, want)
+	}
+
+	if got, want := strings.Join(own.events, ","), "enter own,exit own 201"; got != want {
+		t.Errorf("events of the embedded Instrumenter: %#v != %#v", got, want)
+	}
+}
+
+func TestLatencyRecorder(t *testing.T) {
+	rc := NewLatencyRecorder(10)
+	s := New().
+		UseHandler(write("a")).
+		UseFunc(notFound).
+		Instrument(rc)
+
+	h := s.Handler()
+	for i := 0; i < 20; i++ {
+		rec, req := newTestRequest("GET", "/")
+		h.ServeHTTP(rec, req)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rc.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got:\n%s", rc.String())
+	}
+
+	for _, l := range lines {
+		if !strings.Contains(l, "n=20 p50=") {
+			t.Errorf("unexpected line: %#v", l)
+		}
+	}
+
+	rc.Reset()
+	if got := rc.String(); got != "" {
+		t.Errorf("expected empty dump after Reset, got %#v", got)
+	}
+}
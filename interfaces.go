@@ -18,6 +18,16 @@ type ContextHandler interface {
 	ServeHTTP(ctx Contexter, wr http.ResponseWriter, req *http.Request)
 }
 
+// MiddlewareWithError is like Middleware but returns an error instead of handling it (see Stack.OnError)
+type MiddlewareWithError interface {
+	ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) error
+}
+
+// HandlerWithError is like a http.Handler but returns an error instead of handling it (see Stack.OnError)
+type HandlerWithError interface {
+	ServeHTTP(wr http.ResponseWriter, req *http.Request) error
+}
+
 // Wrapper wraps an http.Handler returning another http.Handler
 type Wrapper interface {
 	Wrap(http.Handler) http.Handler
@@ -31,9 +41,25 @@ type Swapper interface {
 	Swap(replacement interface{})
 }
 
+// ValueContexter stores and retrieves per request data by comparable keys.
+// It is part of Contexter and TransactionContexter and the base of the typed Key.
+type ValueContexter interface {
+	// SetValue sets the given value for the given key, replacing the value of the same key
+	SetValue(key, val interface{})
+
+	// GetValue returns the value for the given key. If there is no value for the key, has is false
+	GetValue(key interface{}) (val interface{}, has bool)
+
+	// DelValue deletes the value for the given key
+	DelValue(key interface{})
+}
+
 // Contexter stores and retrieves per request data.
-// Only one value per type can be stored.
+// Only one Swapper per type can be stored. Values stored via ValueContexter
+// are independent from the Swappers.
 type Contexter interface {
+	// The methods of ValueContexter may be run on the same Contexter concurrently
+	ValueContexter
 
 	// Set the given Swapper, replaces the value of the same type
 	// Set may be run on the same Contexter concurrently
@@ -53,6 +79,32 @@ type Contexter interface {
 	// given function that might be used to call the Set, Get and Del methods inside the transaction.
 	// However that methods must not be used concurrently
 	Transaction(func(TransactionContexter))
+
+	// Snapshot returns a detached copy of the current values without the ResponseWriter.
+	// The copy may still be used after the request has been served, e.g. by background goroutines.
+	// Changes to the copy do not affect the original and vice versa, however stored pointers are shared.
+	Snapshot() Contexter
+
+	// Range calls fn for each stored value until fn returns false. Swappers are passed with their
+	// reflect.Type as key. fn must not modify the Contexter.
+	// Range may be run on the same Contexter concurrently
+	Range(fn func(key, val interface{}) bool)
+
+	// Keys returns the keys of all stored values in no particular order. Swappers are keyed by their reflect.Type.
+	// Keys may be run on the same Contexter concurrently
+	Keys() []interface{}
+
+	// OnBeforeWriteHeader registers fn to be called right before the status code is written to the response
+	// (explicitly or by the first Write), e.g. to set cookies or headers. fn receives the header of the response and the status code.
+	// Hooks are called in reverse order of registration. Hooks that are registered after the header has been
+	// written are never called.
+	// OnBeforeWriteHeader may be run on the same Contexter concurrently
+	OnBeforeWriteHeader(fn func(header http.Header, code int))
+
+	// OnFinish registers fn to be called after the whole stack returned, e.g. for cleanup, metrics or audits.
+	// Hooks are called in reverse order of registration, also if the stack panicked.
+	// OnFinish may be run on the same Contexter concurrently
+	OnFinish(fn func())
 }
 
 // TransactionContexter stores and retrieves per request data via a hidden Contexter.
@@ -60,6 +112,9 @@ type Contexter interface {
 // Only one TransactionContexter might be used at the same time for the same Contexter.
 // No method of a TransactionContexter might be used concurrently
 type TransactionContexter interface {
+	// The methods of ValueContexter may NOT be run on the same TransactionContexter concurrently
+	ValueContexter
+
 	// Set the given Swapper, replaces the value of the same type
 	// Set may NOT be run on the same TransactionContexter concurrently
 	Set(Swapper)
@@ -73,4 +128,13 @@ type TransactionContexter interface {
 	// Del deletes a value of the given type.
 	// Del may NOT be run on the same TransactionContexter concurrently
 	Del(Swapper)
+
+	// Range calls fn for each stored value until fn returns false. Swappers are passed with their
+	// reflect.Type as key. fn must not modify the TransactionContexter.
+	// Range may NOT be run on the same TransactionContexter concurrently
+	Range(fn func(key, val interface{}) bool)
+
+	// Keys returns the keys of all stored values in no particular order. Swappers are keyed by their reflect.Type.
+	// Keys may NOT be run on the same TransactionContexter concurrently
+	Keys() []interface{}
 }
@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package stack
+
+// Key is a typed key for storing values of type T inside a Contexter.
+// In contrast to Swappers there is no need for a Swap method and multiple keys
+// may share the same type T without colliding.
+//
+// Keys are compared by identity, so they should be created once, e.g.
+//
+//	var UserKey = stack.NewKey[User]("user")
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new Key for values of type T. The name is only used for debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name}
+}
+
+// String returns the name of the key
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// Set stores the given value for the key, replacing any previous value of the key
+func (k *Key[T]) Set(ctx ValueContexter, val T) {
+	ctx.SetValue(k, val)
+}
+
+// Get returns the value that is stored for the key.
+// If there is no value, has is false.
+func (k *Key[T]) Get(ctx ValueContexter) (val T, has bool) {
+	v, has := ctx.GetValue(k)
+	if !has {
+		return
+	}
+	val, _ = v.(T)
+	return val, true
+}
+
+// Delete deletes the value that is stored for the key
+func (k *Key[T]) Delete(ctx ValueContexter) {
+	ctx.DelValue(k)
+}
@@ -0,0 +1,53 @@
+//go:build go1.18
+// +build go1.18
+
+package stack
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var (
+	firstName = NewKey[string]("firstName")
+	lastName  = NewKey[string]("lastName")
+)
+
+func setNames(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	firstName.Set(c, "Peter")
+	lastName.Set(c, "Pan")
+	ct := ctx("swapper")
+	c.Set(&ct)
+	next.ServeHTTP(w, r)
+}
+
+func TestKey(t *testing.T) {
+	var s Stack
+	s.UseFuncWithContext(setNames)
+	s.UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+		c.Transaction(func(tc TransactionContexter) {
+			last, _ := lastName.Get(tc)
+			lastName.Set(tc, last+"!")
+		})
+		next.ServeHTTP(w, r)
+	})
+
+	h := s.WrapFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request) {
+		first, _ := firstName.Get(c)
+		last, _ := lastName.Get(c)
+		var ct ctx
+		c.Get(&ct)
+		firstName.Delete(c)
+		_, has := firstName.Get(c)
+		fmt.Fprintf(w, "%s %s %s %v", first, last, ct, has)
+	})
+
+	rec, req := newTestRequest("GET", "/")
+	h.ServeHTTP(rec, req)
+
+	expected := "Peter Pan! swapper false"
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+}
@@ -0,0 +1,106 @@
+package stack
+
+// ErrUnknownLabel is the error returned if there is no middleware with the label inside the stack
+type ErrUnknownLabel string
+
+// Error returns the error message
+func (e ErrUnknownLabel) Error() string {
+	return "unknown label " + string(e)
+}
+
+// ErrDuplicateLabel is the error returned if a label is already used inside the stack
+type ErrDuplicateLabel string
+
+// Error returns the error message
+func (e ErrDuplicateLabel) Error() string {
+	return "duplicate label " + string(e)
+}
+
+// Label labels the middleware that has been added last, so that it can be referenced by
+// InsertBefore, InsertAfter, Replace and Remove.
+// Label panics if the stack is empty, or if the label is already used inside the stack.
+func (s *Stack) Label(label string) *Stack {
+	if len(s.entries) == 0 {
+		panic("no middleware to label")
+	}
+	if s.indexOf(label) != -1 {
+		panic(ErrDuplicateLabel(label))
+	}
+	s.entries[len(s.entries)-1].desc.Label = label
+	return s
+}
+
+// indexOf returns the position of the middleware with the given label or -1 if there is none
+func (s *Stack) indexOf(label string) int {
+	for i, e := range s.entries {
+		if e.desc.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// splice replaces the n middleware at position i by copies of the middleware of st.
+// Labels of st must not be used by the remaining middleware of the stack.
+func (s *Stack) splice(i, n int, st *Stack) error {
+	for _, e := range st.entries {
+		if e.desc.Label == "" {
+			continue
+		}
+		if j := s.indexOf(e.desc.Label); j != -1 && (j < i || j >= i+n) {
+			return ErrDuplicateLabel(e.desc.Label)
+		}
+	}
+	entries := make([]*entry, 0, len(s.entries)-n+len(st.entries))
+	entries = append(entries, s.entries[:i]...)
+	for _, e := range st.entries {
+		cp := *e
+		entries = append(entries, &cp)
+	}
+	entries = append(entries, s.entries[i+n:]...)
+	s.entries = entries
+	return nil
+}
+
+// InsertBefore inserts the middleware of the given stack before the middleware with the given label.
+// The inserted middleware keeps its phase, so the position is only relevant inside the same phase (see Phase).
+func (s *Stack) InsertBefore(label string, st *Stack) error {
+	i := s.indexOf(label)
+	if i == -1 {
+		return ErrUnknownLabel(label)
+	}
+	return s.splice(i, 0, st)
+}
+
+// InsertAfter inserts the middleware of the given stack after the middleware with the given label.
+// The inserted middleware keeps its phase, so the position is only relevant inside the same phase (see Phase).
+func (s *Stack) InsertAfter(label string, st *Stack) error {
+	i := s.indexOf(label)
+	if i == -1 {
+		return ErrUnknownLabel(label)
+	}
+	return s.splice(i+1, 0, st)
+}
+
+// Replace replaces the middleware with the given label by the middleware of the given stack.
+// If the first middleware of the given stack has no label, it takes over the label of the replaced middleware.
+func (s *Stack) Replace(label string, st *Stack) error {
+	i := s.indexOf(label)
+	if i == -1 {
+		return ErrUnknownLabel(label)
+	}
+	err := s.splice(i, 1, st)
+	if err == nil && len(st.entries) > 0 && s.entries[i].desc.Label == "" {
+		s.entries[i].desc.Label = label
+	}
+	return err
+}
+
+// Remove removes the middleware with the given label
+func (s *Stack) Remove(label string) error {
+	i := s.indexOf(label)
+	if i == -1 {
+		return ErrUnknownLabel(label)
+	}
+	return s.splice(i, 1, New())
+}
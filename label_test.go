@@ -0,0 +1,82 @@
+package stack
+
+import (
+	"net/http"
+	"testing"
+)
+
+func labeledStack() *Stack {
+	return New().
+		UseHandler(write("a")).Label("a").
+		UseHandler(write("b")).Label("b").
+		UseHandler(write("c")).Label("c")
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		body string
+		fn   func(s *Stack) error
+	}{
+		{"abc", func(s *Stack) error { return nil }},
+		{"axbc", func(s *Stack) error { return s.InsertBefore("b", New().UseHandler(write("x"))) }},
+		{"abxc", func(s *Stack) error { return s.InsertAfter("b", New().UseHandler(write("x"))) }},
+		{"abcx", func(s *Stack) error { return s.InsertAfter("c", New().UseHandler(write("x"))) }},
+		{"xyac", func(s *Stack) error {
+			if err := s.Remove("b"); err != nil {
+				return err
+			}
+			return s.InsertBefore("a", New().UseHandler(write("x")).UseHandler(write("y")))
+		}},
+		{"axyc", func(s *Stack) error {
+			if err := s.Replace("b", New().UseHandler(write("x")).UseHandler(write("y"))); err != nil {
+				return err
+			}
+			// the label is taken over by x
+			return s.Replace("b", New().UseHandler(write("x")))
+		}},
+	}
+
+	for _, test := range tests {
+		s := labeledStack()
+		if err := test.fn(s); err != nil {
+			t.Errorf("unexpected error for %#v: %s", test.body, err)
+			continue
+		}
+		rec, req := newTestRequest("GET", "/")
+		s.Handler().ServeHTTP(rec, req)
+		assertResponse(t, rec, test.body, 200)
+	}
+}
+
+func TestLabelErrors(t *testing.T) {
+	s := labeledStack()
+	x := New().UseHandler(write("x"))
+
+	tests := map[string]error{
+		"InsertBefore": s.InsertBefore("x", x),
+		"InsertAfter":  s.InsertAfter("x", x),
+		"Replace":      s.Replace("x", x),
+		"Remove":       s.Remove("x"),
+	}
+
+	for method, err := range tests {
+		if err != ErrUnknownLabel("x") {
+			t.Errorf("%s with unknown label returned %v", method, err)
+		}
+	}
+
+	if err := s.InsertAfter("a", New().UseHandler(write("x")).Label("c")); err != ErrDuplicateLabel("c") {
+		t.Errorf("InsertAfter with duplicate label returned %v", err)
+	}
+
+	if err := s.Replace("c", New().UseHandler(write("x")).Label("c")); err != nil {
+		t.Errorf("Replace with own label returned %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != ErrDuplicateLabel("a") {
+			t.Errorf("Label with duplicate label should panic, recovered: %v", p)
+		}
+	}()
+	s.UseFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {}).Label("a")
+}
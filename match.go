@@ -0,0 +1,164 @@
+package stack
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+)
+
+// Matcher is an interface for types that can match against a http.Request
+type Matcher interface {
+	// Match returns if the request matches
+	Match(*http.Request) bool
+}
+
+// MatchFunc is a Matcher based on a function
+type MatchFunc func(*http.Request) bool
+
+// Match implements Matcher for the MatchFunc
+func (mf MatchFunc) Match(req *http.Request) bool {
+	return mf(req)
+}
+
+// conditional is a Wrapper that only uses the middleware if the matcher matches (or does not match, if negate is true)
+type conditional struct {
+	m      Matcher
+	mw     interface{}
+	kind   Kind
+	fn     func(http.Handler) http.Handler
+	negate bool
+}
+
+// wrapWith returns the handler that uses the wrapping function of the middleware for matching requests
+func (c *conditional) wrapWith(fn func(http.Handler) http.Handler, next http.Handler) http.Handler {
+	wrapped := fn(next)
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		if c.m.Match(req) != c.negate {
+			wrapped.ServeHTTP(wr, req)
+			return
+		}
+		next.ServeHTTP(wr, req)
+	})
+}
+
+func (c *conditional) Wrap(next http.Handler) http.Handler {
+	return c.wrapWith(c.fn, next)
+}
+
+func (c *conditional) String() string {
+	cond := "If"
+	if c.negate {
+		cond = "Unless"
+	}
+	return fmt.Sprintf("%s %s: %s", cond, matcherName(c.m), nameOf(c.mw))
+}
+
+// ErrUnsupportedMiddleware is the panic value of UseIf and UseUnless for middleware that has none of the
+// shapes accepted by the UseXXX methods
+type ErrUnsupportedMiddleware struct {
+	// Type is the type of the middleware
+	Type string
+}
+
+// Error returns the error message
+func (e ErrUnsupportedMiddleware) Error() string {
+	return "unsupported middleware " + e.Type
+}
+
+// shapeOf returns the kind and the wrapping function of the given middleware that might have any of the
+// shapes accepted by the UseXXX methods. Wrappers are preferred to other shapes of the same type.
+// For middleware that returns errors, the wrapping function is nil and the withError is returned instead.
+func shapeOf(mw interface{}) (Kind, func(http.Handler) http.Handler, withError) {
+	switch x := mw.(type) {
+	case Wrapper:
+		return KindWrapper, x.Wrap, nil
+	case func(http.Handler) http.Handler:
+		return KindWrapper, x, nil
+	case Middleware:
+		return KindMiddleware, mwHandler(x), nil
+	case func(http.ResponseWriter, *http.Request, http.Handler):
+		return KindMiddleware, mwHandlerFunc(x).Middleware(), nil
+	case ContextMiddleware:
+		return KindContextMiddleware, mwCtxHandler(x), nil
+	case func(Contexter, http.ResponseWriter, *http.Request, http.Handler):
+		return KindContextMiddleware, mwCtxHandlerFunc(x).Middleware(), nil
+	case http.Handler:
+		return KindHandler, before(x.ServeHTTP).Wrap, nil
+	case func(http.ResponseWriter, *http.Request):
+		return KindHandler, before(x).Wrap, nil
+	case ContextHandler:
+		return KindContextHandler, before(ContextHandlerFunc(x.ServeHTTP).ServeHTTP).Wrap, nil
+	case func(Contexter, http.ResponseWriter, *http.Request):
+		return KindContextHandler, before(ContextHandlerFunc(x).ServeHTTP).Wrap, nil
+	case MiddlewareWithError:
+		return KindMiddlewareWithError, nil, mwWithError(x.ServeHTTP)
+	case func(http.ResponseWriter, *http.Request, http.Handler) error:
+		return KindMiddlewareWithError, nil, mwWithError(x)
+	case HandlerWithError:
+		return KindHandlerWithError, nil, handlerWithError(x.ServeHTTP)
+	case func(http.ResponseWriter, *http.Request) error:
+		return KindHandlerWithError, nil, handlerWithError(x)
+	}
+	panic(ErrUnsupportedMiddleware{fmt.Sprintf("%T", mw)})
+}
+
+// newConditional returns the conditional for the given middleware and the withError for the entry,
+// if the middleware returns errors
+func newConditional(m Matcher, mw interface{}, negate bool) (*conditional, withError) {
+	kind, fn, we := shapeOf(mw)
+	c := &conditional{m: m, mw: mw, kind: kind, fn: fn, negate: negate}
+	if we == nil {
+		return c, nil
+	}
+	// the wrapping function is only known, when the error handler of the stack is known
+	c.fn = we(setOrWriteError)
+	return c, func(onError func(error, http.ResponseWriter, *http.Request)) func(http.Handler) http.Handler {
+		inner := we(onError)
+		return func(next http.Handler) http.Handler {
+			return c.wrapWith(inner, next)
+		}
+	}
+}
+
+// matcherName returns the name of the given matcher for the Descriptor
+func matcherName(m Matcher) string {
+	if st, ok := m.(fmt.Stringer); ok {
+		return st.String()
+	}
+	if v := reflect.ValueOf(m); v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T(%v)", m, m)
+}
+
+// UseIf adds the given middleware to the stack, but only uses it for requests
+// that match the given matcher. For other requests the next handler is called directly.
+//
+// The middleware may have any of the shapes that are accepted by the UseXXX methods: Wrapper, Middleware,
+// ContextMiddleware, http.Handler, ContextHandler, MiddlewareWithError, HandlerWithError and the
+// corresponding functions. If a type has several shapes, Wrapper is preferred.
+// UseIf panics with ErrUnsupportedMiddleware for other types.
+//
+// Since the middleware is skipped for some requests, its declared requirements are validated,
+// but the values it provides are not (see ValidateWithContext).
+func (s *Stack) UseIf(m Matcher, mw interface{}) *Stack {
+	c, we := newConditional(m, mw, false)
+	s.push(c.kind, c, c.Wrap)
+	if we != nil {
+		s.setWithError(we)
+	}
+	return s
+}
+
+// UseUnless is like UseIf but only uses the given middleware for requests that do not match the given matcher.
+func (s *Stack) UseUnless(m Matcher, mw interface{}) *Stack {
+	c, we := newConditional(m, mw, true)
+	s.push(c.kind, c, c.Wrap)
+	if we != nil {
+		s.setWithError(we)
+	}
+	return s
+}
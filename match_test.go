@@ -0,0 +1,96 @@
+package stack
+
+import (
+	"net/http"
+	"testing"
+)
+
+func isPostRequest(r *http.Request) bool {
+	return r.Method == "POST"
+}
+
+var isPost = MatchFunc(isPostRequest)
+
+func TestUseIf(t *testing.T) {
+	s := New().
+		UseIf(isPost, write("a")).
+		UseUnless(isPost, New().UseFunc(write("b").ServeHTTPNext).UseWithContext(setCtx("c"))).
+		UseFuncWithContext(writeCtxNext)
+
+	tests := map[string]string{
+		"POST": "a",
+		"GET":  "bc",
+	}
+
+	h := s.HandlerWithContext()
+	for method, body := range tests {
+		rec, req := newTestRequest(method, "/")
+		h.ServeHTTP(rec, req)
+		assertResponse(t, rec, body, 200)
+	}
+
+	descs := s.Describe()
+	if got, want := descs[0].Name, `If github.com/go-on/stack.isPostRequest: stack.write`; got != want {
+		t.Errorf("descs[0].Name == %#v != %#v", got, want)
+	}
+
+	if got, want := descs[1].Name, "Unless github.com/go-on/stack.isPostRequest: *stack.Stack"; got != want {
+		t.Errorf("descs[1].Name == %#v != %#v", got, want)
+	}
+
+	if len(descs[1].Sub) != 2 {
+		t.Errorf("len(descs[1].Sub) == %d != 2", len(descs[1].Sub))
+	}
+}
+
+func TestUseIfShapes(t *testing.T) {
+	errFailed := NewHTTPError(http.StatusTeapot, nil)
+	s := New().
+		UseIf(isPost, func(wr http.ResponseWriter, req *http.Request) { wr.Write([]byte("h")) }).
+		UseIf(isPost, func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+			wr.Write([]byte("m"))
+			next.ServeHTTP(wr, req)
+		}).
+		UseUnless(isPost, setCtx("c")).
+		UseUnless(isPost, func(wr http.ResponseWriter, req *http.Request) error {
+			if req.URL.Path == "/fail" {
+				return errFailed
+			}
+			return nil
+		}).
+		OnError(WriteError).
+		UseFuncWithContext(writeCtxNext)
+
+	tests := []struct {
+		method, path string
+		body         string
+		code         int
+	}{
+		{"POST", "/", "hm", 200},
+		{"GET", "/", "c", 200},
+		{"GET", "/fail", "I'm a teapot", http.StatusTeapot},
+	}
+
+	h := s.HandlerWithContext()
+	for _, test := range tests {
+		rec, req := newTestRequest(test.method, test.path)
+		h.ServeHTTP(rec, req)
+		assertResponse(t, rec, test.body, test.code)
+	}
+
+	kinds := []Kind{KindHandler, KindMiddleware, KindContextMiddleware, KindHandlerWithError}
+	for i, d := range s.Describe()[:4] {
+		if d.Kind != kinds[i] {
+			t.Errorf("s.Describe()[%d].Kind == %s != %s", i, d.Kind, kinds[i])
+		}
+	}
+}
+
+func TestUseIfUnsupported(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(ErrUnsupportedMiddleware); !ok {
+			t.Errorf("UseIf should panic with ErrUnsupportedMiddleware for unsupported middleware")
+		}
+	}()
+	New().UseIf(isPost, 42)
+}
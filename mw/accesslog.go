@@ -0,0 +1,278 @@
+package mw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// LogFormat is the format of the lines written by AccessLog
+type LogFormat int
+
+const (
+	// LogCommon is the Apache Common Log Format
+	LogCommon LogFormat = iota
+
+	// LogCombined is the Apache Combined Log Format (Common Log Format plus referer and user agent)
+	LogCombined
+
+	// LogJSON writes a JSON object per line, including the duration, the time to first byte, the request ID and the Values.
+	// The Values are nested inside the field "values", so that they can not overwrite the other fields.
+	LogJSON
+)
+
+// clfTime is the time layout of the Common Log Format
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog is a middleware that writes a line per request after the request has been served.
+// It records the status, the number of bytes written, the duration, the request ID and selected values
+// of the stack.Contexter (if there is one).
+//
+// If the next handler panics before the header has been written, the status 500 is logged, since that is what
+// the client gets (e.g. from Catch); the JSON format also has the field "panic". The panic is not recovered.
+//
+// The Common and Combined formats are written as specified by Apache, so that they can be parsed by the
+// usual tools. Duration, request ID and Values are only part of the JSON format.
+//
+// An AccessLog must not be copied after first use.
+type AccessLog struct {
+	// Out receives the log lines. If it is nil, os.Stderr is used.
+	Out io.Writer
+
+	// Format is the format of the log lines
+	Format LogFormat
+
+	// RequestIDHeader is the header that carries the request ID, if it has not been set via the RequestID
+	// middleware (see GetRequestID). The response header is checked before the request header.
+	// If it is empty, X-Request-Id is used.
+	RequestIDHeader string
+
+	// UserKey is the key of the user inside the stack.Contexter (see stack.ValueContexter).
+	// The user is formatted via fmt.Sprint. If there is no such value, the user of basic authentication is logged.
+	UserKey interface{}
+
+	// Values maps the names of the fields inside the JSON field "values" to keys of values inside
+	// the stack.Contexter that should be logged
+	Values map[string]interface{}
+
+	// Exclude lists path prefixes of requests that are not logged, e.g. health checks
+	Exclude []string
+
+	// Sample maps path prefixes to the fraction of requests that is logged (between 0 and 1).
+	// The longest matching prefix wins. Requests without matching prefix are always logged.
+	Sample map[string]float64
+
+	mx sync.Mutex
+}
+
+// logged returns whether the request with the given path should be logged
+func (a *AccessLog) logged(path string) bool {
+	for _, prefix := range a.Exclude {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	rate, longest := 1.0, -1
+	for prefix, r := range a.Sample {
+		if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+			rate, longest = r, len(prefix)
+		}
+	}
+
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	default:
+		return rand.Float64() < rate
+	}
+}
+
+func (a *AccessLog) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	if !a.logged(req.URL.Path) {
+		next.ServeHTTP(wr, req)
+		return
+	}
+
+	rec := responsewriter.NewRecorder(wr)
+	start := time.Now()
+	// the panic is not recovered, so that outer middleware (e.g. Catch) sees the original stack trace
+	panicked := true
+	defer func() {
+		a.log(wr, rec, req, start, time.Since(start), panicked)
+	}()
+	next.ServeHTTP(stack.WrapResponseWriter(wr, rec), req)
+	panicked = false
+}
+
+// accessEntry holds the data of a log line
+type accessEntry struct {
+	start     time.Time
+	duration  time.Duration
+	ttfb      time.Duration
+	remote    string
+	user      string
+	requestID string
+	status    int
+	size      int64
+	panicked  bool
+	values    map[string]interface{}
+}
+
+func (a *AccessLog) log(wr http.ResponseWriter, rec *responsewriter.Recorder, req *http.Request, start time.Time, duration time.Duration, panicked bool) {
+	e := accessEntry{
+		start:    start,
+		duration: duration,
+		ttfb:     rec.TimeToFirstByte(),
+		remote:   req.RemoteAddr,
+		status:   rec.Status(),
+		size:     rec.BytesWritten(),
+		panicked: panicked,
+	}
+
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		e.remote = host
+	}
+
+	switch {
+	case panicked && !rec.HeaderWritten():
+		e.status = http.StatusInternalServerError
+	case e.status == 0:
+		e.status = http.StatusOK
+	}
+
+	if e.requestID = GetRequestID(wr, req); e.requestID == "" {
+		hd := a.RequestIDHeader
+		if hd == "" {
+			hd = "X-Request-Id"
+		}
+		if e.requestID = wr.Header().Get(hd); e.requestID == "" {
+			e.requestID = req.Header.Get(hd)
+		}
+	}
+
+	ctx, hasCtx := stack.ContexterFromRequest(req)
+	if !hasCtx {
+		ctx, hasCtx = wr.(stack.Contexter)
+	}
+
+	if hasCtx && a.UserKey != nil {
+		if u, has := ctx.GetValue(a.UserKey); has {
+			e.user = fmt.Sprint(u)
+		}
+	}
+	if e.user == "" {
+		e.user, _, _ = req.BasicAuth()
+	}
+
+	if hasCtx && a.Format == LogJSON && len(a.Values) > 0 {
+		e.values = map[string]interface{}{}
+		for name, key := range a.Values {
+			if val, has := ctx.GetValue(key); has {
+				e.values[name] = val
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	switch a.Format {
+	case LogJSON:
+		writeJSONLog(&buf, &e, req)
+	case LogCombined:
+		writeCommonLog(&buf, &e, req)
+		fmt.Fprintf(&buf, " %q %q", clfField(req.Referer()), clfField(req.UserAgent()))
+	default:
+		writeCommonLog(&buf, &e, req)
+	}
+	buf.WriteByte('\n')
+
+	out := a.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	a.mx.Lock()
+	out.Write(buf.Bytes())
+	a.mx.Unlock()
+}
+
+// requestURI returns the unmodified request target, falling back to the URL for client requests
+func requestURI(req *http.Request) string {
+	if req.RequestURI != "" {
+		return req.RequestURI
+	}
+	return req.URL.RequestURI()
+}
+
+// clfField returns the given string or - if it is empty
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func writeCommonLog(buf *bytes.Buffer, e *accessEntry, req *http.Request) {
+	size := "-"
+	if e.size > 0 {
+		size = strconv.FormatInt(e.size, 10)
+	}
+	fmt.Fprintf(buf, "%s - %s [%s] \"%s %s %s\" %d %s",
+		clfField(e.remote), clfField(e.user), e.start.Format(clfTime),
+		req.Method, requestURI(req), req.Proto, e.status, size,
+	)
+}
+
+func writeJSONLog(buf *bytes.Buffer, e *accessEntry, req *http.Request) {
+	line := map[string]interface{}{
+		"time":        e.start.Format(time.RFC3339Nano),
+		"remote":      e.remote,
+		"method":      req.Method,
+		"uri":         requestURI(req),
+		"proto":       req.Proto,
+		"status":      e.status,
+		"size":        e.size,
+		"duration_ms": float64(e.duration) / float64(time.Millisecond),
+		"ttfb_ms":     float64(e.ttfb) / float64(time.Millisecond),
+		"referer":     req.Referer(),
+		"user_agent":  req.UserAgent(),
+	}
+	if e.user != "" {
+		line["user"] = e.user
+	}
+	if e.requestID != "" {
+		line["request_id"] = e.requestID
+	}
+	if e.panicked {
+		line["panic"] = true
+	}
+	if len(e.values) > 0 {
+		line["values"] = e.values
+	}
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		// some value could not be marshalled, so fall back to the string representation of the values
+		values := map[string]string{}
+		for name, val := range e.values {
+			values[name] = fmt.Sprint(val)
+		}
+		line["values"] = values
+		b, _ = json.Marshal(line)
+	}
+	buf.Write(b)
+}
@@ -0,0 +1,140 @@
+package mw
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+type userKey struct{}
+
+// serveAccessLog serves a request via a stack with the given AccessLog and returns the log output
+func serveAccessLog(a *AccessLog, path string, app func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request)) string {
+	var out bytes.Buffer
+	a.Out = &out
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "test")
+	stack.New().
+		UseFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+			ctx.SetValue(userKey{}, "peter")
+			ctx.SetValue("tenant", "acme")
+			ctx.SetValue("status", "overwritten")
+			next.ServeHTTP(wr, req)
+		}).
+		Use(a).
+		WrapFuncWithContext(app).
+		ServeHTTP(httptest.NewRecorder(), req)
+	return out.String()
+}
+
+func created(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+	wr.Header().Set("X-Request-Id", "abc")
+	wr.WriteHeader(http.StatusCreated)
+	io.WriteString(wr, "created")
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	tests := []struct {
+		format LogFormat
+		line   string
+	}{
+		{LogCommon, `^192\.0\.2\.1 - peter \[[^\]]+\] "GET /a\?b=c HTTP/1\.1" 201 7\n$`},
+		{LogCombined, `^192\.0\.2\.1 - peter \[[^\]]+\] "GET /a\?b=c HTTP/1\.1" 201 7 "http://example\.com/" "test"\n$`},
+	}
+
+	for _, test := range tests {
+		got := serveAccessLog(&AccessLog{Format: test.format, UserKey: userKey{}}, "/a?b=c", created)
+		if !regexp.MustCompile(test.line).MatchString(got) {
+			t.Errorf("format %d: unexpected line %#v", test.format, got)
+		}
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	got := serveAccessLog(&AccessLog{
+		Format:  LogJSON,
+		UserKey: userKey{},
+		Values:  map[string]interface{}{"tenant": "tenant", "status": "status"},
+	}, "/a", created)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &line); err != nil {
+		t.Fatalf("invalid JSON %#v: %s", got, err)
+	}
+
+	expected := map[string]interface{}{
+		"remote":     "192.0.2.1",
+		"method":     "GET",
+		"uri":        "/a",
+		"status":     float64(201),
+		"size":       float64(7),
+		"user":       "peter",
+		"request_id": "abc",
+		"referer":    "http://example.com/",
+		"user_agent": "test",
+	}
+	for field, val := range expected {
+		if line[field] != val {
+			t.Errorf("%s == %#v != %#v", field, line[field], val)
+		}
+	}
+
+	values, _ := line["values"].(map[string]interface{})
+	if values["tenant"] != "acme" || values["status"] != "overwritten" {
+		t.Errorf("values == %#v", line["values"])
+	}
+
+	for _, field := range []string{"time", "duration_ms", "ttfb_ms"} {
+		if _, has := line[field]; !has {
+			t.Errorf("missing field %s", field)
+		}
+	}
+
+	if _, has := line["panic"]; has {
+		t.Errorf("unexpected field panic")
+	}
+}
+
+func TestAccessLogPanicStatus(t *testing.T) {
+	var out bytes.Buffer
+	h := stack.New().
+		Use(Catch(func(p interface{}, wr http.ResponseWriter, req *http.Request) {
+			wr.WriteHeader(http.StatusInternalServerError)
+		})).
+		Use(&AccessLog{Out: &out, Format: LogJSON}).
+		WrapFunc(func(wr http.ResponseWriter, req *http.Request) {
+			panic("boom")
+		})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON %#v: %s", out.String(), err)
+	}
+
+	if line["status"] != float64(500) || line["panic"] != true {
+		t.Errorf("panic should be logged with status 500, got %#v", line)
+	}
+}
+
+func TestAccessLogExclude(t *testing.T) {
+	a := &AccessLog{Exclude: []string{"/healthz"}, Sample: map[string]float64{"/assets/": 0, "/assets/logged/": 1}}
+
+	for path, logged := range map[string]bool{
+		"/healthz":           false,
+		"/assets/app.js":     false,
+		"/assets/logged/app": true,
+		"/":                  true,
+	} {
+		if got := serveAccessLog(a, path, created) != ""; got != logged {
+			t.Errorf("%s: logged == %v != %v", path, got, logged)
+		}
+	}
+}
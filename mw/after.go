@@ -2,6 +2,7 @@ package mw
 
 import (
 	"fmt"
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 	"net/http"
 )
@@ -29,7 +30,7 @@ func (b *after) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.H
 		return true
 	})
 
-	next.ServeHTTP(checked, req)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 	if codeOk {
 		b.Handler.ServeHTTP(wr, req)
 	}
@@ -2,6 +2,7 @@ package mw
 
 import (
 	"fmt"
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 	"net/http"
 )
@@ -30,5 +31,5 @@ func (b *before) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.
 		return true
 	})
 
-	next.ServeHTTP(checked, req)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 }
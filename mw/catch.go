@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -22,6 +23,11 @@ func (c *catch) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.H
 		return true
 	})
 
+	// let the catchFn find the request ID, if it is set later on (see GetRequestID)
+	if !hasContexter(wr, req) {
+		req = withRequestIDSlot(req)
+	}
+
 	defer func() {
 		if p := recover(); p != nil {
 			c.catchFn(p, wr, req)
@@ -30,7 +36,7 @@ func (c *catch) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.H
 		}
 	}()
 
-	next.ServeHTTP(checked, req)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 }
 
 func (c *catch) String() string {
@@ -45,16 +51,27 @@ func Catch(fn func(recovered interface{}, w http.ResponseWriter, r *http.Request
 // PanicCodes returns a middleware that panics if any of the given http codes is set.
 // This allows to get stack traces for debugging. Don't use this in production.
 func PanicCodes(codes ...int) panicCodes {
-	return panicCodes(codes)
+	return panicCodes{codes: codes}
+}
+
+// PanicCodesSpill is like PanicCodes but moves buffered bodies that exceed the given threshold (in bytes)
+// to a temporary file (see responsewriter.NewSpillBuffer)
+func PanicCodesSpill(threshold int64, codes ...int) panicCodes {
+	return panicCodes{codes: codes, spillThreshold: threshold}
 }
 
 // panicCodes is a debugging tool to get a stack trace, if some http.StatusCode is set that is inside the list
-type panicCodes []int
+type panicCodes struct {
+	codes          []int
+	spillThreshold int64
+}
 
 // ServeHTTP wraps the current Responsewriter with a responsewriter.PanicCodes
 func (p panicCodes) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
-	pn := responsewriter.NewPanicCodes(wr, []int(p)...)
-	next.ServeHTTP(pn, req)
+	pn := responsewriter.NewPanicCodes(wr, p.codes...)
+	pn.Threshold = p.spillThreshold
+	defer pn.Close()
+	next.ServeHTTP(stack.WrapResponseWriter(wr, pn), req)
 	// if we got this far, we had no panic :-)
 	pn.FlushAll()
 }
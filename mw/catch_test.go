@@ -0,0 +1,71 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestPanicCodes(t *testing.T) {
+	large := strings.Repeat("x", 100)
+
+	for _, mw := range []stack.Middleware{PanicCodes(http.StatusNotFound), PanicCodesSpill(10, http.StatusNotFound)} {
+		var caught interface{}
+		s := stack.New().
+			Use(Catch(func(p interface{}, wr http.ResponseWriter, req *http.Request) {
+				caught = p
+				wr.WriteHeader(http.StatusInternalServerError)
+			})).
+			Use(mw)
+
+		rec := httptest.NewRecorder()
+		s.WrapFunc(func(wr http.ResponseWriter, req *http.Request) {
+			wr.WriteHeader(http.StatusCreated)
+			io.WriteString(wr, large)
+		}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if caught != nil || rec.Code != http.StatusCreated || rec.Body.String() != large {
+			t.Errorf("%T: unexpected panic %v or response %d %#v", mw, caught, rec.Code, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		s.WrapFunc(func(wr http.ResponseWriter, req *http.Request) {
+			io.WriteString(wr, large)
+			http.NotFound(wr, req)
+		}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if caught == nil || rec.Code != http.StatusInternalServerError {
+			t.Errorf("%T: expected panic, got response %d", mw, rec.Code)
+		}
+	}
+}
+
+func TestCatchHeldBack(t *testing.T) {
+	app := func(wr http.ResponseWriter, req *http.Request) {
+		wr.Header().Set("Content-Type", "text/html")
+		wr.WriteHeader(http.StatusCreated)
+		// short enough to be held back completely
+		io.WriteString(wr, "<p>")
+		panic("boom")
+	}
+
+	for _, mw := range []stack.Middleware{&Minify{}, &Replace{Strings: []string{"</p>", "</div>"}}, &InjectHTML{Body: "<script>"}} {
+		rec := httptest.NewRecorder()
+		stack.New().
+			Use(Catch(func(p interface{}, wr http.ResponseWriter, req *http.Request) {
+				wr.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(wr, "caught")
+			})).
+			Use(mw).
+			WrapFunc(app).
+			ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if rec.Code != http.StatusInternalServerError || rec.Body.String() != "caught" {
+			t.Errorf("%T: response %d %#v, expected the response of Catch", mw, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -18,7 +19,7 @@ func GZip(w http.ResponseWriter, r *http.Request, next http.Handler) {
 	w.Header().Set("Content-Encoding", "gzip")
 	gz := responsewriter.NewGZIP(w)
 	defer gz.Close()
-	next.ServeHTTP(gz, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, gz), r)
 }
 
 // Deflate compresses the body written by the next handlers with the level of the int
@@ -32,7 +33,7 @@ func (d Deflate) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Han
 	w.Header().Set("Content-Encoding", "deflate")
 	df := responsewriter.NewDeflate(w, int(d))
 	defer df.Close()
-	next.ServeHTTP(df, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, df), r)
 }
 
 // Compress compresses the body written by the next handlers via either gzip or deflate, if the
@@ -44,7 +45,7 @@ func (d Compress) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Ha
 		w.Header().Set("Content-Encoding", "gzip")
 		gz := responsewriter.NewGZIP(w)
 		defer gz.Close()
-		next.ServeHTTP(gz, r)
+		next.ServeHTTP(stack.WrapResponseWriter(w, gz), r)
 		return
 	}
 
@@ -52,7 +53,7 @@ func (d Compress) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Ha
 		w.Header().Set("Content-Encoding", "deflate")
 		df := responsewriter.NewDeflate(w, int(d))
 		defer df.Close()
-		next.ServeHTTP(df, r)
+		next.ServeHTTP(stack.WrapResponseWriter(w, df), r)
 		return
 	}
 
@@ -3,6 +3,7 @@ package mw
 import (
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -31,7 +32,7 @@ func (c ContentType) ServeHTTP(wr http.ResponseWriter, req *http.Request, next h
 		return true
 	})
 
-	next.ServeHTTP(checked, req)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 
 	if !bodyWritten {
 		c.SetContentType(checked)
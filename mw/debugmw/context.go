@@ -0,0 +1,140 @@
+package debugmw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// ContextDump is a stack.ContextMiddleware that renders the values stored inside the stack.Contexter
+// per request. Each value is shown with the name of its key (the type name for Swappers)
+// and its %#v or JSON representation.
+//
+// The values are collected after the next handler returned. To set the Header or append the HTML
+// panel the response is buffered, so ContextDump is meant for development only.
+type ContextDump struct {
+	// Header is the name of the response header the values are added to. If empty, no header is set.
+	Header string
+
+	// Logger logs the values if it is not nil
+	Logger *log.Logger
+
+	// HTML appends a debug panel to text/html responses
+	HTML bool
+
+	// JSON renders the values as JSON instead of %#v
+	JSON bool
+}
+
+// ContextEntry is a value inside a stack.Contexter as rendered by ContextDump
+type ContextEntry struct {
+	// Key is the name of the key or the type name for Swappers
+	Key string
+
+	// Value is the rendered value
+	Value string
+}
+
+func (c *ContextDump) ServeHTTP(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	if c.Header == "" && !c.HTML {
+		next.ServeHTTP(wr, req)
+		c.log(req, c.Entries(ctx))
+		return
+	}
+
+	buf := responsewriter.NewBuffer(wr)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, buf), req)
+	entries := c.Entries(ctx)
+	c.log(req, entries)
+
+	if c.Header != "" {
+		for _, e := range entries {
+			buf.Header().Add(c.Header, e.Key+"="+e.Value)
+		}
+	}
+
+	if c.HTML && strings.HasPrefix(buf.Header().Get("Content-Type"), "text/html") {
+		body := appendPanel(buf.Body(), entries)
+		buf.Buffer.Reset()
+		buf.Buffer.Write(body)
+		buf.Header().Del("Content-Length")
+	}
+
+	buf.FlushAll()
+}
+
+func (c *ContextDump) log(req *http.Request, entries []ContextEntry) {
+	if c.Logger == nil {
+		return
+	}
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = e.Key + "=" + e.Value
+	}
+	c.Logger.Printf("%s %s context: %s", req.Method, req.URL.String(), strings.Join(parts, " "))
+}
+
+// Entries returns the rendered values of the given Contexter sorted by key.
+// The original stack.ResponseWriter is skipped.
+func (c *ContextDump) Entries(ctx stack.Contexter) (entries []ContextEntry) {
+	ctx.Range(func(key, val interface{}) bool {
+		if _, isRW := val.(*stack.ResponseWriter); isRW {
+			return true
+		}
+		entries = append(entries, ContextEntry{Key: keyName(key), Value: c.render(val)})
+		return true
+	})
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Key < entries[b].Key })
+	return
+}
+
+func (c *ContextDump) render(val interface{}) string {
+	if c.JSON {
+		if b, err := json.Marshal(val); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%#v", val)
+}
+
+func keyName(key interface{}) string {
+	switch k := key.(type) {
+	case reflect.Type:
+		return k.String()
+	case fmt.Stringer:
+		return k.String()
+	case string:
+		return k
+	default:
+		return fmt.Sprintf("%#v", key)
+	}
+}
+
+// appendPanel inserts the debug panel before the closing body tag or appends it to the body
+func appendPanel(body []byte, entries []ContextEntry) []byte {
+	var panel bytes.Buffer
+	panel.WriteString(`<div id="stack-context-dump" style="font-family:monospace;border-top:2px solid #999;padding:4px">`)
+	panel.WriteString(`<table><tr><th>key</th><th>value</th></tr>`)
+	for _, e := range entries {
+		fmt.Fprintf(&panel, `<tr><td>%s</td><td>%s</td></tr>`, html.EscapeString(e.Key), html.EscapeString(e.Value))
+	}
+	panel.WriteString(`</table></div>`)
+
+	idx := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if idx < 0 {
+		return append(body, panel.Bytes()...)
+	}
+	res := make([]byte, 0, len(body)+panel.Len())
+	res = append(res, body[:idx]...)
+	res = append(res, panel.Bytes()...)
+	return append(res, body[idx:]...)
+}
@@ -0,0 +1,93 @@
+package debugmw
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-on/stack/stacktest"
+)
+
+type user struct {
+	Name string
+}
+
+func (u *user) Swap(repl interface{}) {
+	*u = *(repl.(*user))
+}
+
+func TestContextDumpHeader(t *testing.T) {
+	tests := []struct {
+		json     bool
+		expected []string
+	}{
+		{false, []string{`*debugmw.user=&debugmw.user{Name:"Bob"}`, `name="x"`}},
+		{true, []string{`*debugmw.user={"Name":"Bob"}`, `name="x"`}},
+	}
+
+	for i, test := range tests {
+		res := stacktest.ContextMiddleware(t, &ContextDump{Header: "X-Context", JSON: test.json}).
+			Set(&user{Name: "Bob"}).
+			SetValue("name", "x").
+			NextResponds(200, "hello").
+			Run().
+			ExpectStatus(200).
+			ExpectBody("hello")
+
+		// the internal *stack.ResponseWriter must not show up
+		if got := res.Response.Header()["X-Context"]; !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("[%d] header X-Context == %#v, expected %#v", i, got, test.expected)
+		}
+	}
+}
+
+func TestContextDumpLog(t *testing.T) {
+	var buf bytes.Buffer
+	stacktest.ContextMiddleware(t, &ContextDump{Logger: log.New(&buf, "", 0)}).
+		Request("GET", "/path").
+		SetValue("name", "x").
+		NextResponds(200, "hello").
+		Run().
+		ExpectStatus(200).
+		ExpectBody("hello")
+
+	expected := "GET /path context: name=\"x\"\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("log == %#v, expected %#v", got, expected)
+	}
+}
+
+func TestContextDumpHTML(t *testing.T) {
+	panel := `<div id="stack-context-dump" style="font-family:monospace;border-top:2px solid #999;padding:4px">` +
+		`<table><tr><th>key</th><th>value</th></tr><tr><td>name</td><td>&#34;&lt;x&gt;&#34;</td></tr></table></div>`
+
+	tests := []struct {
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"text/html; charset=utf-8", "<html><body>hi</body></html>", "<html><body>hi" + panel + "</body></html>"},
+		{"text/html", "<p>hi</p>", "<p>hi</p>" + panel},
+		{"text/plain", "<p>hi</p>", "<p>hi</p>"},
+		{"application/json", `{"a":1}`, `{"a":1}`},
+	}
+
+	for i, test := range tests {
+		res := stacktest.ContextMiddleware(t, &ContextDump{HTML: true}).
+			SetValue("name", "<x>").
+			NextResponds(200, test.body, "Content-Type", test.contentType, "Content-Length", "100").
+			Run().
+			ExpectStatus(200).
+			ExpectHeader("Content-Type", test.contentType)
+
+		if got := res.Response.Body.String(); got != test.expected {
+			t.Errorf("[%d] body == %#v, expected %#v", i, got, test.expected)
+		}
+
+		if strings.HasPrefix(test.contentType, "text/html") && res.Response.Header().Get("Content-Length") != "" {
+			t.Errorf("[%d] Content-Length must be removed when the panel is added", i)
+		}
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"net/http"
 	"strings"
 
-	"gopkg.in/go-on/wrap.v2"
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
 )
 
 // DelResponseHeader removes response headers that are identical to the string
@@ -17,7 +18,7 @@ func (rh DelResponseHeader) ServeHTTP(w http.ResponseWriter, r *http.Request, ne
 	bodyWritten := false
 	comp := strings.TrimSpace(strings.ToLower(string(rh)))
 
-	checked := wrap.NewPeek(w, func(ck *wrap.Peek) bool {
+	checked := responsewriter.NewPeek(w, func(ck *responsewriter.Peek) bool {
 		hd := ck.Header()
 		for k := range hd {
 			k = strings.TrimSpace(strings.ToLower(k))
@@ -31,7 +32,7 @@ func (rh DelResponseHeader) ServeHTTP(w http.ResponseWriter, r *http.Request, ne
 		return true
 	})
 
-	next.ServeHTTP(checked, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, checked), r)
 
 	if !bodyWritten {
 		hd := checked.Header()
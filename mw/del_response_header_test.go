@@ -0,0 +1,33 @@
+package mw
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/stacktest"
+)
+
+func TestDelResponseHeader(t *testing.T) {
+	stacktest.Middleware(t, DelResponseHeader("X-Debug")).
+		NextResponds(http.StatusCreated, "body", "X-Debug-Time", "1ms", "X-Other", "yes").
+		Run().
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader("X-Debug-Time", "").
+		ExpectHeader("X-Other", "yes").
+		ExpectBody("body")
+
+	stacktest.Middleware(t, DelResponseHeader("x-debug")).
+		NextResponds(http.StatusNoContent, "", "X-Debug", "yes").
+		Run().
+		ExpectStatus(http.StatusNoContent).
+		ExpectHeader("X-Debug", "")
+
+	stacktest.Stack(t, stack.New().Use(DelResponseHeader("X-Debug"))).
+		Next(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			if _, ok := wr.(stack.Contexter); !ok {
+				t.Errorf("next handler should get the Contexter")
+			}
+		})).
+		Run()
+}
@@ -7,44 +7,56 @@ import (
 	"github.com/go-on/stack/responsewriter"
 )
 
-// Error a type based on error that should be saved by a stack.Contexter (response writer)
-type Error struct {
-	Err error
-}
-
-// Swap implements the stack.Swapper interface.
-func (e *Error) Swap(repl interface{}) {
-	*e = *(repl.(*Error))
-}
+// Error a type based on error that should be saved by a stack.Contexter (response writer).
+// It is an alias of stack.Error.
+type Error = stack.Error
 
 // SetError stores the given error inside the given stack.Contexter if err is not nil
 func SetError(err error, ctx stack.Contexter) {
-	if err == nil {
-		return
-	}
-	e := &Error{err}
-	ctx.Set(e)
+	stack.SetError(err, ctx)
 }
 
 // GetError gets the error out of the given stack.Contexter.
 // If there is no error, nil is returned.
 func GetError(ctx stack.Contexter) error {
-	var e Error
-	if ctx.Get(&e) {
-		return e.Err
-	}
-	return nil
+	return stack.GetError(ctx)
+}
+
+// SetsError may be embedded into middleware that stores errors via SetError. It declares that the
+// middleware provides the Error and requires a previous ErrorHandler (see stack.Provider and stack.Requirer).
+type SetsError struct{}
+
+// Provides declares that the middleware provides the Error
+func (SetsError) Provides() []interface{} {
+	return []interface{}{&Error{}}
+}
+
+// Requires declares that the middleware needs a previous ErrorHandler
+func (SetsError) Requires() []interface{} {
+	return []interface{}{stack.ErrorHandling}
 }
 
 // ErrorHandler is a function that handles an error, if an error has been set inside the stack.Contexter
 // via SetError(). It acts as a middleware that passes to the next http.Handler if there is no error inside
-// the Contexter, otherwise it handles the error by calling the function
+// the Contexter, otherwise it handles the error by calling the function.
+//
+// An ErrorHandler may also be set as error handler of a stack via stack.Stack.OnError. Use stack.StatusCode
+// to get the status code of typed errors like stack.HTTPError.
+//
+// As a middleware it declares that it handles the errors, so that ValidateWithContext reports middleware that
+// stores errors (see SetsError) without a previous ErrorHandler. The Error is not required, since it is usually
+// stored by the app, which is no middleware.
 type ErrorHandler func(error, http.ResponseWriter, *http.Request)
 
+// Provides declares that the ErrorHandler handles the errors of the following middleware and the app
+func (fn ErrorHandler) Provides() []interface{} {
+	return []interface{}{stack.ErrorHandling}
+}
+
 func (fn ErrorHandler) ServeHTTP(ctx stack.Contexter, rw http.ResponseWriter, req *http.Request, next http.Handler) {
 	// returns true, if error happened and was handled, otherwise false
 	var handleError = func(rw http.ResponseWriter, req *http.Request) bool {
-		err := GetError(rw.(stack.Contexter))
+		err := GetError(ctx)
 		if err != nil {
 			fn(err, rw, req)
 			return true
@@ -61,7 +73,7 @@ func (fn ErrorHandler) ServeHTTP(ctx stack.Contexter, rw http.ResponseWriter, re
 		return true
 	})
 
-	next.ServeHTTP(checked, req)
+	next.ServeHTTP(stack.WrapResponseWriter(rw, checked), req)
 
 	if !bodywritten && !handleError(rw, req) {
 		checked.FlushMissing()
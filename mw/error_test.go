@@ -0,0 +1,58 @@
+package mw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+type setsError struct{ SetsError }
+
+func (setsError) ServeHTTP(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	SetError(errors.New("failed"), ctx)
+}
+
+func writeErr(err error, wr http.ResponseWriter, req *http.Request) {
+	http.Error(wr, err.Error(), http.StatusInternalServerError)
+}
+
+func TestErrorHandlerDependencies(t *testing.T) {
+	s := stack.New().UseWithContext(ErrorHandler(writeErr)).UseWithContext(setsError{})
+	if err := s.ValidateWithContext(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		stack    *stack.Stack
+		required string
+	}{
+		{stack.New().UseWithContext(setsError{}), "error handling"},
+		{stack.New().UseWithContext(setsError{}).UseWithContext(ErrorHandler(writeErr)), "error handling"},
+	}
+
+	for i, test := range tests {
+		errs, _ := test.stack.ValidateWithContext().(stack.ErrDependencies)
+		if len(errs) != 1 || errs[0].Required != test.required {
+			t.Errorf("[%d] expected missing %s, got %#v", i, test.required, errs)
+		}
+	}
+}
+
+func TestErrorHandlerWithApp(t *testing.T) {
+	s := stack.New().UseWithContext(ErrorHandler(writeErr))
+	if err := s.ValidateWithContext(); err != nil {
+		t.Errorf("an ErrorHandler without middleware that stores errors should be valid: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.MustWrapFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		SetError(errors.New("app failed"), ctx)
+	}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "app failed\n" {
+		t.Errorf("error of the app should be handled, got %d %#v", rec.Code, rec.Body.String())
+	}
+}
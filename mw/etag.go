@@ -1,28 +1,36 @@
 package mw
 
 import (
-	"bytes"
 	"crypto/md5"
 	"fmt"
 	"hash"
 	"net/http"
 	"strings"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 	"gopkg.in/go-on/method.v1"
 )
 
-type etag struct{}
+type etag struct {
+	spillThreshold int64
+}
 
 // ETag buffers the body writes of the next handler and calculates a md5 Hash based on the Content-Type + Body
 // combination and sets it as etag in the response header.
 // It does so only for GET and HEAD requests. For GET requests the buffered body is flushed to the underlying response writer.
 var ETag = etag{}
 
+// ETagSpill returns an ETag middleware that moves buffered bodies that exceed the given threshold (in bytes)
+// to a temporary file (see responsewriter.NewSpillBuffer)
+func ETagSpill(threshold int64) etag {
+	return etag{spillThreshold: threshold}
+}
+
 type etaggedWriter struct {
 	*responsewriter.Peek
 	h       hash.Hash
-	buf     *bytes.Buffer
+	buf     *responsewriter.Buffer
 	gotData bool
 }
 
@@ -54,10 +62,11 @@ func (e etag) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handle
 
 	// cache for non HEAD methods
 	if m != method.HEAD {
-		et.buf = bytes.NewBuffer(nil)
+		et.buf = responsewriter.NewSpillBuffer(w, e.spillThreshold)
+		defer et.buf.Close()
 	}
 
-	next.ServeHTTP(et, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, et), r)
 	if et.IsOk() && et.gotData {
 		et.Header().Set("ETag", fmt.Sprintf("%x", et.h.Sum(nil)))
 	}
@@ -117,7 +126,7 @@ func (i ifNoneMatch) ServeHTTP(w http.ResponseWriter, r *http.Request, next http
 		return true
 	})
 
-	next.ServeHTTP(checked, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, checked), r)
 
 	checked.FlushMissing()
 }
@@ -153,7 +162,7 @@ func (i *ifMatch) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Ha
 	checkedHead := responsewriter.NewPeek(w, nil)
 
 	headReq, _ := http.NewRequest("HEAD", r.URL.Path, nil)
-	i.Handler.ServeHTTP(checkedHead, headReq)
+	i.Handler.ServeHTTP(stack.WrapResponseWriter(w, checkedHead), headReq)
 
 	var etag string
 	if checkedHead.IsOk() {
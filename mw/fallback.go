@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -26,7 +27,7 @@ func (f *fallback) ServeHTTP(wr http.ResponseWriter, req *http.Request, next htt
 	})
 
 	for _, h := range f.handlers {
-		h.ServeHTTP(checked, req)
+		h.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 		if checked.HasChanged() {
 			if _, has := f.ignoreCodes[checked.Code]; !has {
 				checked.FlushMissing()
@@ -3,6 +3,7 @@ package mw
 import (
 	"fmt"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 	"gopkg.in/go-on/method.v1"
 
@@ -25,7 +26,7 @@ func (f *filterBody) ServeHTTP(w http.ResponseWriter, r *http.Request, next http
 		ck.FlushCode()
 		return false
 	})
-	next.ServeHTTP(checked, r)
+	next.ServeHTTP(stack.WrapResponseWriter(w, checked), r)
 
 	checked.FlushMissing()
 }
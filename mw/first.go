@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -20,7 +21,7 @@ func (f first) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Ha
 	})
 
 	for _, h := range f.handlers {
-		h.ServeHTTP(checked, req)
+		h.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 		if checked.HasChanged() {
 			checked.FlushMissing()
 			return
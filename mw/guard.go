@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -23,7 +24,7 @@ func (g *guard) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.H
 		ck.FlushCode()
 		return true
 	})
-	g.Handler.ServeHTTP(checked, req)
+	g.Handler.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 	if checked.HasChanged() {
 		checked.FlushMissing()
 		return
@@ -3,6 +3,7 @@ package mw
 import (
 	"net/http"
 
+	"github.com/go-on/stack"
 	"github.com/go-on/stack/responsewriter"
 )
 
@@ -38,7 +39,7 @@ func (h head) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Han
 			req.Method = "HEAD"
 		}()
 
-		next.ServeHTTP(checked, req)
+		next.ServeHTTP(stack.WrapResponseWriter(wr, checked), req)
 
 		checked.FlushMissing()
 		return
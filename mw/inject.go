@@ -0,0 +1,52 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// InjectHTML injects HTML snippets into the text/html responses of the next handlers just before
+// </head> and </body>, e.g. analytics, a debug toolbar or a live reload script (see responsewriter.NewHTMLInjector).
+// If a tag is absent, its snippet is appended. Other responses are left untouched.
+// Responses without Content-Type header are detected like net/http does (see http.DetectContentType).
+//
+// In contrast to Around, Before and After, full HTML documents stay valid.
+//
+// It must be added after Compress, GZip, Deflate and ETag, so that they receive the injected body.
+// Compressed bodies are left untouched:
+//
+//	stack.New().Use(mw.Compress(6)).Use(mw.ETag).Use(&mw.InjectHTML{Body: "<script src=\"/live.js\"></script>"})
+type InjectHTML struct {
+	// Head is injected before </head>
+	Head string
+
+	// Body is injected before </body>
+	Body string
+
+	// Snippets returns further snippets that are injected after Head and Body, e.g. a script with the
+	// CSP nonce of the stack.Contexter. It is called when the first byte of a text/html response is written.
+	Snippets func(wr http.ResponseWriter, req *http.Request) (head, body string)
+}
+
+func (in *InjectHTML) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	inj := responsewriter.NewHTMLInjector(wr, func() (head, body []byte) {
+		h, b := in.Head, in.Body
+		if in.Snippets != nil {
+			sh, sb := in.Snippets(wr, req)
+			h, b = h+sh, b+sb
+		}
+		return []byte(h), []byte(b)
+	})
+	next.ServeHTTP(stack.WrapResponseWriter(wr, inj), req)
+	// no defer: on panic, the held back response must not be written, so that Catch can respond
+	inj.Close()
+}
+
+// Wrap implements the stack.Wrapper interface
+func (in *InjectHTML) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		in.ServeHTTP(wr, req, next)
+	})
+}
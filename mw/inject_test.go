@@ -0,0 +1,66 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/stacktest"
+)
+
+type nonceKey struct{}
+
+func TestInjectHTML(t *testing.T) {
+	in := &InjectHTML{
+		Head: "<link>",
+		Body: "<script>",
+		Snippets: func(wr http.ResponseWriter, req *http.Request) (head, body string) {
+			ctx, _ := stack.ContexterFromRequest(req)
+			if ctx == nil {
+				ctx = wr.(stack.Contexter)
+			}
+			nonce, _ := ctx.GetValue(nonceKey{})
+			return "", "<script nonce=" + nonce.(string) + ">"
+		},
+	}
+
+	stacktest.Middleware(t, in).
+		Next(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			wr.(stack.Contexter).SetValue(nonceKey{}, "abc")
+			wr.Header().Set("Content-Type", "text/html")
+			io.WriteString(wr, "<html><head></head><body></BODY></html>")
+		})).
+		Run().
+		ExpectBody("<html><head><link></head><body><script><script nonce=abc></BODY></html>")
+
+	stacktest.Middleware(t, in).
+		NextResponds(http.StatusOK, `{"a":"</body>"}`, "Content-Type", "application/json").
+		Run().
+		ExpectBody(`{"a":"</body>"}`)
+}
+
+func TestInjectHTMLCompress(t *testing.T) {
+	in := &InjectHTML{Body: "<script>"}
+	app := func(wr http.ResponseWriter, req *http.Request) {
+		wr.Header().Set("Content-Type", "text/html")
+		io.WriteString(wr, "<body></body>")
+	}
+
+	tests := []struct {
+		name string
+		h    http.Handler
+		body string
+	}{
+		{"after Compress", stack.New().Use(Compress(6)).Use(in).WrapFunc(app), "<body><script></body>"},
+		{"after GZip", stack.New().UseFunc(GZip).Use(in).WrapFunc(app), "<body><script></body>"},
+		// the injector sees the compressed bytes, so it must pass them through
+		{"before Compress", stack.New().Use(in).Use(Compress(6)).WrapFunc(app), "<body></body>"},
+	}
+
+	for _, test := range tests {
+		if _, body := serveGzipped(t, test.h); body != test.body {
+			t.Errorf("%s: body == %#v != %#v", test.name, body, test.body)
+		}
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"net/http"
 )
 
-// a simple request logger
+// a simple request logger that includes the request ID, if there is one (see RequestID)
 type logger struct{}
 
 func (l logger) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
-	log.Printf("%s %s", r.Method, r.URL.String())
+	if id := GetRequestID(w, r); id != "" {
+		log.Printf("[%s] %s %s", id, r.Method, r.URL.String())
+	} else {
+		log.Printf("%s %s", r.Method, r.URL.String())
+	}
 	next.ServeHTTP(w, r)
 }
 
@@ -3,21 +3,16 @@ package mw
 import (
 	"net/http"
 	"regexp"
+
+	"github.com/go-on/stack"
 )
 
-// Matcher is an interface for types that can match against a http.Request
-type Matcher interface {
-	// Match returns if the request matches
-	Match(*http.Request) bool
-}
+// Matcher is an interface for types that can match against a http.Request.
+// It is the same as stack.Matcher, so that matchers may be used with stack.Stack.UseIf
+type Matcher = stack.Matcher
 
 // MatchFunc is a Matcher based on a function
-type MatchFunc func(*http.Request) bool
-
-// Match implements Matcher for the MatchFunc
-func (mf MatchFunc) Match(req *http.Request) bool {
-	return mf(req)
-}
+type MatchFunc = stack.MatchFunc
 
 // And logically combines different matchers to a single matcher
 // that only matches if all matchers match.
@@ -0,0 +1,33 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// Minify minifies the HTML, CSS, JavaScript and JSON bodies written by the next handlers (see responsewriter.Minify).
+//
+// It must be added after Compress, GZip, Deflate and ETag, so that they receive the minified body:
+//
+//	stack.New().Use(mw.Compress(6)).Use(mw.ETag).Use(&mw.Minify{})
+type Minify struct {
+	// Minifiers maps media types to their responsewriter.Minifier.
+	// If it is nil, responsewriter.DefaultMinifiers is used.
+	Minifiers map[string]responsewriter.Minifier
+}
+
+func (m *Minify) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	mn := responsewriter.NewMinify(wr, m.Minifiers)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, mn), req)
+	// no defer: on panic, the held back response must not be written, so that Catch can respond
+	mn.Close()
+}
+
+// Wrap implements the stack.Wrapper interface
+func (m *Minify) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		m.ServeHTTP(wr, req, next)
+	})
+}
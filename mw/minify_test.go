@@ -0,0 +1,68 @@
+package mw
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/stacktest"
+)
+
+const (
+	minifyHTML    = "<html>\n  <body>\n    <p>  hello  </p>\n  </body>\n</html>\n"
+	minifiedHTML  = "<html> <body> <p> hello </p> </body> </html>"
+	minifyHTMLLen = "55"
+)
+
+func minifyApp(wr http.ResponseWriter, req *http.Request) {
+	wr.Header().Set("Content-Type", "text/html")
+	wr.Header().Set("Content-Length", minifyHTMLLen)
+	io.WriteString(wr, minifyHTML)
+}
+
+func TestMinify(t *testing.T) {
+	stacktest.Wrapper(t, &Minify{}).
+		Next(http.HandlerFunc(minifyApp)).
+		Run().
+		ExpectNextWriterWrapped().
+		ExpectNextWriteHeldBack().
+		ExpectHeader("Content-Length", "").
+		ExpectBody(minifiedHTML)
+}
+
+func TestMinifyCompressETag(t *testing.T) {
+	h := stack.New().Use(Compress(6)).Use(ETag).Use(&Minify{}).WrapFunc(minifyApp)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding == %#v != %#v", got, "gzip")
+	}
+
+	if got := rec.Header().Get("Content-Length"); got == minifyHTMLLen {
+		t.Errorf("Content-Length of the original body should be removed")
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+
+	if string(body) != minifiedHTML {
+		t.Errorf("body == %#v != %#v", string(body), minifiedHTML)
+	}
+
+	etag := fmt.Sprintf("%x", md5.Sum([]byte("text/html"+minifiedHTML)))
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag should be calculated from the minified body: %#v != %#v", got, etag)
+	}
+}
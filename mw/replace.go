@@ -0,0 +1,57 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// Replace rewrites the bodies written by the next handlers on the fly (see responsewriter.Replacer),
+// e.g. to replace asset hostnames, to inject a CSRF token into forms or to rewrite absolute URLs
+// of an app that is mounted under a different mountpoint.
+//
+// It must be added after Compress, GZip, Deflate and ETag, so that they receive the replaced body.
+// Compressed bodies are left untouched:
+//
+//	stack.New().Use(mw.Compress(6)).Use(mw.ETag).Use(&mw.Replace{Strings: []string{"http://cdn.old", "https://cdn.new"}})
+//
+// Since it is also a stack.Wrapper, it may be passed to mux.MountWrapped.
+type Replace struct {
+	// ContentTypes restricts the replacements to responses with one of the content types (prefixes).
+	// If it is empty, all responses are affected.
+	ContentTypes []string
+
+	// Strings are pairs of old and new strings, e.g. "http://cdn.old", "https://cdn.new".
+	// A last old string without new string is ignored.
+	Strings []string
+
+	// Replacements returns further replacements that are applied after the Strings, e.g. depending on the request.
+	// Since replacements hold state, new ones must be returned for each request.
+	Replacements func(req *http.Request) []*responsewriter.Replacement
+}
+
+func (rp *Replace) replacements(req *http.Request) []*responsewriter.Replacement {
+	repl := make([]*responsewriter.Replacement, 0, len(rp.Strings)/2)
+	for i := 0; i+1 < len(rp.Strings); i += 2 {
+		repl = append(repl, responsewriter.StringReplacement(rp.Strings[i], rp.Strings[i+1]))
+	}
+	if rp.Replacements != nil {
+		repl = append(repl, rp.Replacements(req)...)
+	}
+	return repl
+}
+
+func (rp *Replace) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	rw := responsewriter.NewReplacer(wr, rp.ContentTypes, rp.replacements(req)...)
+	next.ServeHTTP(stack.WrapResponseWriter(wr, rw), req)
+	// no defer: on panic, the held back response must not be written, so that Catch can respond
+	rw.Close()
+}
+
+// Wrap implements the stack.Wrapper interface
+func (rp *Replace) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		rp.ServeHTTP(wr, req, next)
+	})
+}
@@ -0,0 +1,95 @@
+package mw
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+	"github.com/go-on/stack/stacktest"
+)
+
+func TestReplace(t *testing.T) {
+	rp := &Replace{
+		ContentTypes: []string{"text/html"},
+		Strings:      []string{"http://cdn.old", "https://cdn.new", "ignored"},
+		Replacements: func(req *http.Request) []*responsewriter.Replacement {
+			return []*responsewriter.Replacement{
+				responsewriter.RegexpReplacement(regexp.MustCompile(`href="/`), `href="`+req.URL.Query().Get("mount")+`/`, 16),
+			}
+		},
+	}
+
+	stacktest.Wrapper(t, rp).
+		Request("GET", "/?mount=/app").
+		NextResponds(http.StatusOK, `<a href="/x"><img src="http://cdn.old/a.png">ignored`, "Content-Type", "text/html", "Content-Length", "53").
+		Run().
+		ExpectNextWriterWrapped().
+		ExpectNextWritePassedThrough().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Length", "").
+		ExpectBody(`<a href="/app/x"><img src="https://cdn.new/a.png">ignored`)
+
+	stacktest.Wrapper(t, rp).
+		NextResponds(http.StatusOK, `http://cdn.old`, "Content-Type", "text/plain").
+		Run().
+		ExpectNextWritePassedThrough().
+		ExpectBody(`http://cdn.old`)
+
+	stacktest.Middleware(t, &Replace{Strings: []string{"", "x"}}).
+		NextResponds(http.StatusOK, "unchanged").
+		Run().
+		ExpectBody("unchanged")
+}
+
+// serveGzipped serves a GET request that accepts gzip and returns the recorder and the decompressed body
+func serveGzipped(t *testing.T, h http.Handler) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding == %#v != %#v", got, "gzip")
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec, string(body)
+}
+
+func TestReplaceCompress(t *testing.T) {
+	rp := &Replace{Strings: []string{"old", "new"}}
+	app := func(wr http.ResponseWriter, req *http.Request) {
+		wr.Header().Set("Content-Type", "text/plain")
+		io.WriteString(wr, "old body")
+	}
+
+	tests := []struct {
+		name string
+		h    http.Handler
+		body string
+	}{
+		{"after Compress", stack.New().Use(Compress(6)).Use(rp).WrapFunc(app), "new body"},
+		{"after GZip", stack.New().UseFunc(GZip).Use(rp).WrapFunc(app), "new body"},
+		// the Replacer sees the compressed bytes, so it must pass them through
+		{"before Compress", stack.New().Use(rp).Use(Compress(6)).WrapFunc(app), "old body"},
+	}
+
+	for _, test := range tests {
+		if _, body := serveGzipped(t, test.h); body != test.body {
+			t.Errorf("%s: body == %#v != %#v", test.name, body, test.body)
+		}
+	}
+}
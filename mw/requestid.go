@@ -0,0 +1,135 @@
+package mw
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-on/stack"
+)
+
+// requestIDKey is the key of the request ID inside the stack.Contexter and the context.Context of the request
+type requestIDKey struct{}
+
+// requestIDSlotKey is the key of a requestIDSlot inside the context.Context of the request
+type requestIDSlotKey struct{}
+
+// requestIDSlot receives the request ID for middleware that runs before RequestID without a stack.Contexter (see Catch)
+type requestIDSlot struct {
+	id string
+}
+
+// hasContexter returns true if there is a stack.Contexter for the request that receives the request ID,
+// so that no requestIDSlot is needed
+func hasContexter(wr http.ResponseWriter, req *http.Request) bool {
+	if _, ok := stack.ContexterFromRequest(req); ok {
+		return true
+	}
+	_, ok := wr.(stack.Contexter)
+	return ok
+}
+
+// withRequestIDSlot returns a request with an empty slot for the request ID
+func withRequestIDSlot(req *http.Request) *http.Request {
+	return req.WithContext(stdcontext.WithValue(req.Context(), requestIDSlotKey{}, &requestIDSlot{}))
+}
+
+// RequestID is a middleware that tags every request with an ID.
+// A valid ID of an incoming request header is kept, otherwise a new ID is generated.
+// The ID is stored inside the context.Context of the request and inside the stack.Contexter (if there is one)
+// and it is echoed in the response header. Use GetRequestID to retrieve it.
+type RequestID struct {
+	// Header is the request and response header that carries the ID. If it is empty, X-Request-Id is used.
+	Header string
+
+	// Generate returns a new ID. If it is nil, NewRequestID is used.
+	Generate func() string
+
+	// Valid reports whether an incoming ID is acceptable. If it is nil, ValidRequestID is used.
+	Valid func(id string) bool
+}
+
+// NewRequestID returns a random ID of 32 hex characters
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ValidRequestID reports whether the given ID has between 1 and 128 characters
+// and consists of ASCII letters, digits and the characters - _ . : only
+func ValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RequestID) ServeHTTP(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	hd := r.Header
+	if hd == "" {
+		hd = "X-Request-Id"
+	}
+
+	valid := r.Valid
+	if valid == nil {
+		valid = ValidRequestID
+	}
+
+	id := req.Header.Get(hd)
+	if !valid(id) {
+		if r.Generate == nil {
+			id = NewRequestID()
+		} else {
+			id = r.Generate()
+		}
+	}
+
+	if ctx, ok := stack.ContexterFromRequest(req); ok {
+		ctx.SetValue(requestIDKey{}, id)
+	} else if ctx, ok := wr.(stack.Contexter); ok {
+		ctx.SetValue(requestIDKey{}, id)
+	}
+
+	if slot, ok := req.Context().Value(requestIDSlotKey{}).(*requestIDSlot); ok {
+		slot.id = id
+	}
+
+	wr.Header().Set(hd, id)
+	next.ServeHTTP(wr, req.WithContext(stdcontext.WithValue(req.Context(), requestIDKey{}, id)))
+}
+
+// GetRequestID returns the ID that has been set by the RequestID middleware or an empty string if there is none.
+// The ID is looked up inside the context.Context of the request and inside the stack.Contexter of the
+// request or the http.ResponseWriter. Middleware that runs before RequestID only finds the ID via the
+// stack.Contexter, except for the handlers of Catch that always find it.
+func GetRequestID(wr http.ResponseWriter, req *http.Request) string {
+	if id, ok := req.Context().Value(requestIDKey{}).(string); ok {
+		return id
+	}
+
+	if slot, ok := req.Context().Value(requestIDSlotKey{}).(*requestIDSlot); ok && slot.id != "" {
+		return slot.id
+	}
+
+	ctx, ok := stack.ContexterFromRequest(req)
+	if !ok {
+		ctx, ok = wr.(stack.Contexter)
+	}
+	if ok {
+		if id, has := ctx.GetValue(requestIDKey{}); has {
+			return id.(string)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestRequestID(t *testing.T) {
+	hex32 := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		mw       *RequestID
+		header   string
+		incoming string
+		expected *regexp.Regexp
+	}{
+		{&RequestID{}, "X-Request-Id", "", hex32},
+		{&RequestID{}, "X-Request-Id", "abc-123", regexp.MustCompile(`^abc-123$`)},
+		{&RequestID{}, "X-Request-Id", "no spaces", hex32},
+		{&RequestID{Header: "X-Trace", Generate: func() string { return "gen" }}, "X-Trace", "", regexp.MustCompile(`^gen$`)},
+		{&RequestID{Valid: func(string) bool { return false }}, "X-Request-Id", "abc", hex32},
+	}
+
+	for i, test := range tests {
+		var seen string
+		h := stack.New().Use(test.mw).WrapFunc(func(wr http.ResponseWriter, req *http.Request) {
+			seen = GetRequestID(wr, req)
+		})
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.incoming != "" {
+			req.Header.Set(test.header, test.incoming)
+		}
+		h.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(test.header)
+		if !test.expected.MatchString(id) {
+			t.Errorf("[%d] response header %s == %#v, expected %s", i, test.header, id, test.expected)
+		}
+
+		if seen != id {
+			t.Errorf("[%d] GetRequestID == %#v != %#v", i, seen, id)
+		}
+	}
+}
+
+func TestRequestIDInContexter(t *testing.T) {
+	var outer string
+	h := stack.New().
+		UseFunc(func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+			next.ServeHTTP(wr, req)
+			outer = GetRequestID(wr, req)
+		}).
+		Use(&RequestID{Generate: func() string { return "gen" }}).
+		WrapFuncWithContext(func(stack.Contexter, http.ResponseWriter, *http.Request) {})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if outer != "gen" {
+		t.Errorf("middleware before RequestID should find the ID via the Contexter, got %#v", outer)
+	}
+}
+
+func TestRequestIDCatch(t *testing.T) {
+	for _, withContext := range []bool{false, true} {
+		var caught string
+		s := stack.New().
+			Use(Catch(func(p interface{}, wr http.ResponseWriter, req *http.Request) {
+				caught = GetRequestID(wr, req)
+				wr.WriteHeader(http.StatusInternalServerError)
+			})).
+			Use(&RequestID{Generate: func() string { return "gen" }})
+
+		app := func(wr http.ResponseWriter, req *http.Request) {
+			_, hasSlot := req.Context().Value(requestIDSlotKey{}).(*requestIDSlot)
+			if withContext && hasSlot {
+				t.Errorf("Catch should not add a slot if there is a Contexter")
+			}
+			panic("boom")
+		}
+
+		var h http.Handler
+		if withContext {
+			h = s.WrapFuncWithContext(func(_ stack.Contexter, wr http.ResponseWriter, req *http.Request) { app(wr, req) })
+		} else {
+			h = s.WrapFunc(app)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if caught != "gen" {
+			t.Errorf("with context %v: Catch handler got request ID %#v", withContext, caught)
+		}
+	}
+}
@@ -18,7 +18,9 @@ func (s *switcher) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.H
 }
 
 // Switch switches between 2 middleware functions via call of a decider function
-// If the decider function returns true, truemw is run, otherwise the falsemw
+// If the decider function returns true, truemw is run, otherwise the falsemw.
+// To apply a middleware only to some requests, stack.Stack.UseIf and stack.Stack.UseUnless might be
+// more convenient.
 func Switch(decider func(r *http.Request) bool, truemw, falsemw func(w http.ResponseWriter, r *http.Request, next http.Handler)) *switcher {
 	return &switcher{
 		mw:     [2]func(w http.ResponseWriter, r *http.Request, next http.Handler){truemw, falsemw},
@@ -0,0 +1,98 @@
+package mw
+
+import (
+	stdcontext "context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// Timeout is a replacement for http.TimeoutHandler that keeps the stack.Contexter working for
+// the next handlers.
+//
+// The next handler runs in its own goroutine with a request whose context is canceled after Duration.
+// It receives a buffered http.ResponseWriter and a fork of the stack.Contexter (see stack.Contexter.Snapshot)
+// that is passed via the request context and the http.ResponseWriter.
+// If the next handler finishes in time, the buffered response is written and the changes to the fork
+// are merged back (see stack.Merge). Otherwise the status 503 and Body is written and all changes are dropped.
+//
+// Since the response is buffered, the next handlers can not flush or hijack the connection
+// (also not via http.ResponseController).
+type Timeout struct {
+	// Duration is the maximum time for the next handler
+	Duration time.Duration
+
+	// SpillThreshold is the number of bytes of the buffered response that are kept in memory.
+	// Larger responses are moved to a temporary file (see responsewriter.NewSpillBuffer).
+	// If it is 0, the response is always kept in memory.
+	SpillThreshold int64
+
+	// Body is written with the status 503 (Service Unavailable) on timeout.
+	// If it is empty, the status text is written.
+	Body string
+}
+
+// timeoutWriter is the buffered http.ResponseWriter of the next handler that carries the fork
+type timeoutWriter struct {
+	*responsewriter.Buffer
+	stack.Contexter
+}
+
+// Unwrap returns nil instead of the underlying http.ResponseWriter, so that the next handler can't
+// hijack the connection or set its deadlines via http.ResponseController, even after the timeout.
+func (tw timeoutWriter) Unwrap() http.ResponseWriter {
+	return nil
+}
+
+// timeoutResult is the result of the next handler
+type timeoutResult struct {
+	panicked bool
+	p        interface{}
+}
+
+func (t Timeout) ServeHTTP(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	c, cancel := stdcontext.WithTimeout(req.Context(), t.Duration)
+	defer cancel()
+
+	fork := ctx.Snapshot()
+	r := stack.RequestWithContexter(req.WithContext(c), fork)
+	buf := responsewriter.NewSpillBuffer(wr, t.SpillThreshold)
+	buf.Contexter = fork
+	tw := timeoutWriter{buf, fork}
+
+	done := make(chan timeoutResult, 1)
+	go func() {
+		var res timeoutResult
+		defer func() {
+			if p := recover(); p != nil {
+				res = timeoutResult{true, p}
+			}
+			done <- res
+		}()
+		next.ServeHTTP(tw, r)
+	}()
+
+	select {
+	case res := <-done:
+		if res.panicked {
+			panic(res.p)
+		}
+		stack.Merge(ctx, fork)
+		buf.FlushAll()
+	case <-c.Done():
+		// the next handler still writes to the buffer, so its temporary file is removed when it returns
+		go func() {
+			<-done
+			buf.Close()
+		}()
+		wr.WriteHeader(http.StatusServiceUnavailable)
+		body := t.Body
+		if body == "" {
+			body = http.StatusText(http.StatusServiceUnavailable)
+		}
+		io.WriteString(wr, body)
+	}
+}
@@ -0,0 +1,164 @@
+package mw
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-on/stack"
+)
+
+type timeoutKey struct{}
+
+// serveTimeout serves a request via a stack with the given Timeout and next handler
+// and returns the value of timeoutKey inside the Contexter after the Timeout returned
+func serveTimeout(t Timeout, next func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request)) (rec *httptest.ResponseRecorder, val interface{}) {
+	rec = httptest.NewRecorder()
+	stack.New().
+		UseFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+			next.ServeHTTP(wr, req)
+			val, _ = ctx.GetValue(timeoutKey{})
+		}).
+		UseWithContext(t).
+		WrapFuncWithContext(next).
+		ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	return
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	rec, val := serveTimeout(Timeout{Duration: time.Second}, func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		ctx.SetValue(timeoutKey{}, "set")
+		wr.Header().Set("X-Test", "yes")
+		wr.WriteHeader(http.StatusCreated)
+		io.WriteString(wr, "created")
+	})
+
+	if val != "set" {
+		t.Errorf("value == %#v, expected it to be merged", val)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("rec.Code == %d != %d", rec.Code, http.StatusCreated)
+	}
+
+	if got := rec.Header().Get("X-Test"); got != "yes" {
+		t.Errorf("header X-Test == %#v != %#v", got, "yes")
+	}
+
+	if got := rec.Body.String(); got != "created" {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, "created")
+	}
+}
+
+func TestTimeoutExceeded(t *testing.T) {
+	canceled := make(chan error, 1)
+	lateWritten := make(chan struct{})
+
+	rec, val := serveTimeout(Timeout{Duration: 10 * time.Millisecond, Body: "too slow"}, func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		ctx.SetValue(timeoutKey{}, "set")
+		<-req.Context().Done()
+		canceled <- req.Context().Err()
+		wr.Header().Set("X-Test", "late")
+		io.WriteString(wr, "late")
+		close(lateWritten)
+	})
+
+	select {
+	case err := <-canceled:
+		if err == nil {
+			t.Errorf("request context should be canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("request context was not canceled")
+	}
+	<-lateWritten
+
+	if val != nil {
+		t.Errorf("value == %#v, expected changes to be dropped", val)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("rec.Code == %d != %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	if got := rec.Body.String(); got != "too slow" {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, "too slow")
+	}
+
+	if got := rec.Header().Get("X-Test"); got != "" {
+		t.Errorf("late header X-Test should be discarded, got %#v", got)
+	}
+}
+
+func TestTimeoutDefaultBody(t *testing.T) {
+	rec, _ := serveTimeout(Timeout{Duration: time.Millisecond}, func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	})
+
+	if got, want := rec.Body.String(), http.StatusText(http.StatusServiceUnavailable); got != want {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, want)
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %#v, expected the panic of the handler", p)
+		}
+	}()
+
+	serveTimeout(Timeout{Duration: time.Second}, func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+}
+
+// connRecorder is a httptest.ResponseRecorder that tracks if the connection has been hijacked or got a deadline
+type connRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked, deadline bool
+}
+
+func (c *connRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c.hijacked = true
+	return nil, nil, errors.New("not supported")
+}
+
+func (c *connRecorder) SetWriteDeadline(time.Time) error {
+	c.deadline = true
+	return nil
+}
+
+func TestTimeoutResponseController(t *testing.T) {
+	rec := &connRecorder{ResponseRecorder: httptest.NewRecorder()}
+	errs := make(chan error, 2)
+
+	stack.New().
+		UseWithContext(Timeout{Duration: 10 * time.Millisecond}).
+		WrapFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+			rc := http.NewResponseController(wr)
+			errs <- rc.SetWriteDeadline(time.Now())
+			_, _, err := rc.Hijack()
+			errs <- err
+		}).
+		ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("error == %v, expected http.ErrNotSupported", err)
+		}
+	}
+
+	if rec.hijacked || rec.deadline {
+		t.Errorf("the next handler reached the connection after the timeout")
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("rec.Code == %d != %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
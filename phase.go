@@ -0,0 +1,67 @@
+package stack
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Phase orders the middleware of a stack. Middleware of a lower phase is called before middleware
+// of a higher phase, no matter in which order it has been added. Inside a phase, the order of adding is kept.
+// Custom phases may be defined between the predefined ones, e.g. PhaseSecurity + 10.
+type Phase int
+
+const (
+	// PhaseRecover is the phase of middleware that recovers from panics
+	PhaseRecover Phase = 100
+
+	// PhaseObserve is the phase of middleware that observes the request, e.g. logging and metrics
+	PhaseObserve Phase = 200
+
+	// PhaseSecurity is the phase of middleware that secures the request, e.g. authentication and CSRF protection
+	PhaseSecurity Phase = 300
+
+	// PhaseSession is the phase of middleware that handles sessions
+	PhaseSession Phase = 400
+
+	// PhaseApp is the phase of the application middleware. It is the default phase
+	PhaseApp Phase = 500
+)
+
+var phaseNames = map[Phase]string{
+	PhaseRecover:  "recover",
+	PhaseObserve:  "observe",
+	PhaseSecurity: "security",
+	PhaseSession:  "session",
+	PhaseApp:      "app",
+}
+
+// String returns the name of the phase
+func (p Phase) String() string {
+	if name, has := phaseNames[p]; has {
+		return name
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// InPhase puts the middleware that has been added last into the given phase, e.g.
+//
+//	s.Use(mw.Catch(catcher)).InPhase(stack.PhaseRecover)
+//
+// InPhase panics if the stack is empty.
+func (s *Stack) InPhase(p Phase) *Stack {
+	if len(s.entries) == 0 {
+		panic("no middleware to put into a phase")
+	}
+	s.entries[len(s.entries)-1].desc.Phase = p
+	return s
+}
+
+// ordered returns the middleware of the stack in the order of their phases
+func (s *Stack) ordered() []*entry {
+	entries := make([]*entry, len(s.entries))
+	copy(entries, s.entries)
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].desc.Phase < entries[b].desc.Phase
+	})
+	return entries
+}
@@ -0,0 +1,31 @@
+package stack
+
+import "testing"
+
+func TestPhases(t *testing.T) {
+	s := New().
+		UseWrapper(writeString("app1 ")).
+		UseWrapper(writeString("session ")).InPhase(PhaseSession).
+		UseWrapper(writeString("recover ")).InPhase(PhaseRecover).
+		UseWrapper(writeString("app2 ")).
+		UseWrapper(writeString("custom ")).InPhase(PhaseSecurity + 10).
+		UseWrapper(writeString("observe1 ")).InPhase(PhaseObserve).
+		UseWrapper(writeString("observe2 ")).InPhase(PhaseObserve)
+
+	rec, req := newTestRequest("GET", "/")
+	s.Handler().ServeHTTP(rec, req)
+
+	expected := "recover observe1 observe2 custom session app1 app2 "
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("rec.Body.String() == %#v != %#v", got, expected)
+	}
+
+	descs := s.Describe()
+	if descs[0].Phase != PhaseRecover || descs[len(descs)-1].Phase != PhaseApp {
+		t.Errorf("Describe should return the middleware in the order of the phases")
+	}
+
+	if got := (PhaseSecurity + 10).String(); got != "Phase(310)" {
+		t.Errorf("(PhaseSecurity + 10).String() == %#v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package stack
+
+//go:generate go run gen_preserve.go
+
+import (
+	"io"
+	"net/http"
+)
+
+// Unwrapper is implemented by http.ResponseWriter wrappers to return the wrapped http.ResponseWriter.
+// It is respected by http.ResponseController.
+type Unwrapper interface {
+	Unwrap() http.ResponseWriter
+}
+
+// base is embedded in each writer returned by WrapResponseWriter
+type base struct {
+	http.ResponseWriter
+}
+
+// Unwrap returns the outer http.ResponseWriter that was passed to WrapResponseWriter
+func (b base) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// readFrom implements io.ReaderFrom by copying to the Write method of the outer http.ResponseWriter,
+// so that it is not bypassed
+type readFrom struct {
+	w io.Writer
+}
+
+func (r readFrom) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(r.w, src)
+}
+
+// WrapResponseWriter returns a http.ResponseWriter that writes to outer and implements exactly the optional
+// interfaces of inner, which is the http.ResponseWriter that is wrapped by outer. The optional interfaces
+// are http.Flusher, http.Hijacker, http.Pusher, http.CloseNotifier and io.ReaderFrom.
+//
+// If outer implements an optional interface itself, its method is used, otherwise the method of inner.
+// An exception is io.ReaderFrom which copies via the Write method of outer, if outer does not implement it.
+// The returned http.ResponseWriter implements Contexter if inner does and Unwrapper, returning outer.
+//
+// Wrappers that replace the http.ResponseWriter for the next handler should pass the result, e.g.
+//
+//	buf := responsewriter.NewBuffer(wr)
+//	next.ServeHTTP(stack.WrapResponseWriter(wr, buf), req)
+func WrapResponseWriter(inner, outer http.ResponseWriter) http.ResponseWriter {
+	ctx, _ := inner.(Contexter)
+	return preserve(inner, outer, ctx)
+}
+
+// preserve is like WrapResponseWriter but with the given Contexter that might be nil
+func preserve(inner, outer http.ResponseWriter, ctx Contexter) http.ResponseWriter {
+	var (
+		mask int
+		fl   http.Flusher
+		hj   http.Hijacker
+		ps   http.Pusher
+		cn   http.CloseNotifier
+		rf   io.ReaderFrom
+		ok   bool
+	)
+
+	if ctx != nil {
+		mask |= 1
+	}
+
+	if fl, ok = inner.(http.Flusher); ok {
+		mask |= 2
+		if o, has := outer.(http.Flusher); has {
+			fl = o
+		}
+	}
+
+	if hj, ok = inner.(http.Hijacker); ok {
+		mask |= 4
+		if o, has := outer.(http.Hijacker); has {
+			hj = o
+		}
+	}
+
+	if ps, ok = inner.(http.Pusher); ok {
+		mask |= 8
+		if o, has := outer.(http.Pusher); has {
+			ps = o
+		}
+	}
+
+	if cn, ok = inner.(http.CloseNotifier); ok {
+		mask |= 16
+		if o, has := outer.(http.CloseNotifier); has {
+			cn = o
+		}
+	}
+
+	if _, ok = inner.(io.ReaderFrom); ok {
+		mask |= 32
+		if rf, ok = outer.(io.ReaderFrom); !ok {
+			rf = readFrom{outer}
+		}
+	}
+
+	return combine(mask, base{outer}, ctx, fl, hj, ps, cn, rf)
+}
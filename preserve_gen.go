@@ -0,0 +1,464 @@
+// Code generated by gen_preserve.go; DO NOT EDIT.
+
+package stack
+
+import (
+	"io"
+	"net/http"
+)
+
+// combine returns a http.ResponseWriter that embeds base and the optional interfaces that are set in mask
+func combine(mask int, b base, ctx Contexter, fl http.Flusher, hj http.Hijacker, ps http.Pusher, cn http.CloseNotifier, rf io.ReaderFrom) http.ResponseWriter {
+	switch mask {
+	case 0:
+		return struct {
+			base
+		}{b}
+	case 1:
+		return struct {
+			base
+			Contexter
+		}{b, ctx}
+	case 2:
+		return struct {
+			base
+			http.Flusher
+		}{b, fl}
+	case 3:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+		}{b, ctx, fl}
+	case 4:
+		return struct {
+			base
+			http.Hijacker
+		}{b, hj}
+	case 5:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+		}{b, ctx, hj}
+	case 6:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+		}{b, fl, hj}
+	case 7:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+		}{b, ctx, fl, hj}
+	case 8:
+		return struct {
+			base
+			http.Pusher
+		}{b, ps}
+	case 9:
+		return struct {
+			base
+			Contexter
+			http.Pusher
+		}{b, ctx, ps}
+	case 10:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+		}{b, fl, ps}
+	case 11:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Pusher
+		}{b, ctx, fl, ps}
+	case 12:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+		}{b, hj, ps}
+	case 13:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.Pusher
+		}{b, ctx, hj, ps}
+	case 14:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{b, fl, hj, ps}
+	case 15:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{b, ctx, fl, hj, ps}
+	case 16:
+		return struct {
+			base
+			http.CloseNotifier
+		}{b, cn}
+	case 17:
+		return struct {
+			base
+			Contexter
+			http.CloseNotifier
+		}{b, ctx, cn}
+	case 18:
+		return struct {
+			base
+			http.Flusher
+			http.CloseNotifier
+		}{b, fl, cn}
+	case 19:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.CloseNotifier
+		}{b, ctx, fl, cn}
+	case 20:
+		return struct {
+			base
+			http.Hijacker
+			http.CloseNotifier
+		}{b, hj, cn}
+	case 21:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.CloseNotifier
+		}{b, ctx, hj, cn}
+	case 22:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+		}{b, fl, hj, cn}
+	case 23:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+		}{b, ctx, fl, hj, cn}
+	case 24:
+		return struct {
+			base
+			http.Pusher
+			http.CloseNotifier
+		}{b, ps, cn}
+	case 25:
+		return struct {
+			base
+			Contexter
+			http.Pusher
+			http.CloseNotifier
+		}{b, ctx, ps, cn}
+	case 26:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+			http.CloseNotifier
+		}{b, fl, ps, cn}
+	case 27:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Pusher
+			http.CloseNotifier
+		}{b, ctx, fl, ps, cn}
+	case 28:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{b, hj, ps, cn}
+	case 29:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{b, ctx, hj, ps, cn}
+	case 30:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{b, fl, hj, ps, cn}
+	case 31:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{b, ctx, fl, hj, ps, cn}
+	case 32:
+		return struct {
+			base
+			io.ReaderFrom
+		}{b, rf}
+	case 33:
+		return struct {
+			base
+			Contexter
+			io.ReaderFrom
+		}{b, ctx, rf}
+	case 34:
+		return struct {
+			base
+			http.Flusher
+			io.ReaderFrom
+		}{b, fl, rf}
+	case 35:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			io.ReaderFrom
+		}{b, ctx, fl, rf}
+	case 36:
+		return struct {
+			base
+			http.Hijacker
+			io.ReaderFrom
+		}{b, hj, rf}
+	case 37:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			io.ReaderFrom
+		}{b, ctx, hj, rf}
+	case 38:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{b, fl, hj, rf}
+	case 39:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{b, ctx, fl, hj, rf}
+	case 40:
+		return struct {
+			base
+			http.Pusher
+			io.ReaderFrom
+		}{b, ps, rf}
+	case 41:
+		return struct {
+			base
+			Contexter
+			http.Pusher
+			io.ReaderFrom
+		}{b, ctx, ps, rf}
+	case 42:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{b, fl, ps, rf}
+	case 43:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{b, ctx, fl, ps, rf}
+	case 44:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, hj, ps, rf}
+	case 45:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, ctx, hj, ps, rf}
+	case 46:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, fl, hj, ps, rf}
+	case 47:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{b, ctx, fl, hj, ps, rf}
+	case 48:
+		return struct {
+			base
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, cn, rf}
+	case 49:
+		return struct {
+			base
+			Contexter
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, cn, rf}
+	case 50:
+		return struct {
+			base
+			http.Flusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, fl, cn, rf}
+	case 51:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, fl, cn, rf}
+	case 52:
+		return struct {
+			base
+			http.Hijacker
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, hj, cn, rf}
+	case 53:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, hj, cn, rf}
+	case 54:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, fl, hj, cn, rf}
+	case 55:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, fl, hj, cn, rf}
+	case 56:
+		return struct {
+			base
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ps, cn, rf}
+	case 57:
+		return struct {
+			base
+			Contexter
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, ps, cn, rf}
+	case 58:
+		return struct {
+			base
+			http.Flusher
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, fl, ps, cn, rf}
+	case 59:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, fl, ps, cn, rf}
+	case 60:
+		return struct {
+			base
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, hj, ps, cn, rf}
+	case 61:
+		return struct {
+			base
+			Contexter
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, hj, ps, cn, rf}
+	case 62:
+		return struct {
+			base
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, fl, hj, ps, cn, rf}
+	case 63:
+		return struct {
+			base
+			Contexter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{b, ctx, fl, hj, ps, cn, rf}
+	default:
+		panic("unreachable")
+	}
+}
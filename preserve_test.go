@@ -0,0 +1,123 @@
+package stack
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// hijackRecorder is a httptest.ResponseRecorder that is also a http.Hijacker
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+// upperWriter writes uppercased to the underlying http.ResponseWriter
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (u *upperWriter) Write(b []byte) (int, error) {
+	return u.ResponseWriter.Write([]byte(strings.ToUpper(string(b))))
+}
+
+func TestWrapResponseWriter(t *testing.T) {
+	inner := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := WrapResponseWriter(inner, &upperWriter{inner})
+
+	if _, ok := w.(http.Flusher); !ok {
+		t.Errorf("%T should be a http.Flusher", w)
+	}
+
+	if _, ok := w.(http.Pusher); ok {
+		t.Errorf("%T should not be a http.Pusher", w)
+	}
+
+	if _, ok := w.(Contexter); ok {
+		t.Errorf("%T should not be a Contexter", w)
+	}
+
+	if _, ok := w.(io.ReaderFrom); ok {
+		t.Errorf("%T should not be a io.ReaderFrom", w)
+	}
+
+	if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+		t.Errorf("Hijack via http.ResponseController returned error: %v", err)
+	}
+
+	if !inner.hijacked {
+		t.Errorf("Hijack should be passed to the inner http.ResponseWriter")
+	}
+
+	if _, ok := w.(Unwrapper).Unwrap().(*upperWriter); !ok {
+		t.Errorf("Unwrap should return the outer http.ResponseWriter")
+	}
+
+	w.Write([]byte("hello"))
+	if got, expected := inner.Body.String(), "HELLO"; got != expected {
+		t.Errorf("inner.Body.String() == %#v != %#v", got, expected)
+	}
+}
+
+// readFromRecorder is a httptest.ResponseRecorder that is also a io.ReaderFrom
+type readFromRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r *readFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+func TestWrapResponseWriterReadFrom(t *testing.T) {
+	inner := &readFromRecorder{httptest.NewRecorder()}
+	w := WrapResponseWriter(inner, &upperWriter{inner})
+
+	rf, ok := w.(io.ReaderFrom)
+	if !ok {
+		t.Fatalf("%T should be a io.ReaderFrom", w)
+	}
+
+	rf.ReadFrom(strings.NewReader("hello"))
+	if got, expected := inner.Body.String(), "HELLO"; got != expected {
+		t.Errorf("ReadFrom should not bypass the outer Write: %#v != %#v", got, expected)
+	}
+}
+
+func TestContextKeepsFlusher(t *testing.T) {
+	var flushed bool
+	h := New().
+		UseFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request, next http.Handler) {
+			c.OnBeforeWriteHeader(func(h http.Header, code int) { h.Set("X-Hook", "yes") })
+			next.ServeHTTP(w, r)
+		}).
+		WrapFuncWithContext(func(c Contexter, w http.ResponseWriter, r *http.Request) {
+			if fl, ok := w.(http.Flusher); ok {
+				fl.Flush()
+				flushed = true
+			}
+		})
+
+	rec, req := newTestRequest("GET", "/")
+	h.ServeHTTP(rec, req)
+
+	if !flushed {
+		t.Errorf("http.ResponseWriter of a handler with context should be a http.Flusher")
+	}
+
+	if !rec.Flushed {
+		t.Errorf("recorder should be flushed")
+	}
+
+	if got := rec.Header().Get("X-Hook"); got != "yes" {
+		t.Errorf("hooks should run before flushing, header X-Hook == %#v", got)
+	}
+}
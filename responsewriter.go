@@ -32,9 +32,13 @@ func ReclaimResponseWriter(rw http.ResponseWriter) http.ResponseWriter {
 
 // Flush is a helper that flushes the buffer in the  underlying response writer if it is a http.Flusher.
 // The http.ResponseWriter might also be a Contexter if it allows the retrieval of the underlying
-// ResponseWriter. Ok returns if the underlying ResponseWriter was a http.Flusher
+// ResponseWriter. If the given http.ResponseWriter is a http.Flusher itself (e.g. via WrapResponseWriter), it is flushed directly.
+// Ok returns if the underlying ResponseWriter was a http.Flusher
 func Flush(rw http.ResponseWriter) (ok bool) {
-	w := ReclaimResponseWriter(rw)
+	w := rw
+	if _, is := rw.(http.Flusher); !is {
+		w = ReclaimResponseWriter(rw)
+	}
 	if fl, is := w.(http.Flusher); is {
 		fl.Flush()
 		return true
@@ -45,7 +49,10 @@ func Flush(rw http.ResponseWriter) (ok bool) {
 // CloseNotify is the same for http.CloseNotifier as Flush is for http.Flusher
 // ok tells if it was a CloseNotifier
 func CloseNotify(rw http.ResponseWriter) (ch <-chan bool, ok bool) {
-	w := ReclaimResponseWriter(rw)
+	w := rw
+	if _, is := rw.(http.CloseNotifier); !is {
+		w = ReclaimResponseWriter(rw)
+	}
 	if cl, is := w.(http.CloseNotifier); is {
 		ch = cl.CloseNotify()
 		ok = true
@@ -57,7 +64,10 @@ func CloseNotify(rw http.ResponseWriter) (ch <-chan bool, ok bool) {
 // Hijack is the same for http.Hijacker as Flush is for http.Flusher
 // ok tells if it was a Hijacker
 func Hijack(rw http.ResponseWriter) (c net.Conn, brw *bufio.ReadWriter, err error, ok bool) {
-	w := ReclaimResponseWriter(rw)
+	w := rw
+	if _, is := rw.(http.Hijacker); !is {
+		w = ReclaimResponseWriter(rw)
+	}
 	if hj, is := w.(http.Hijacker); is {
 		c, brw, err = hj.Hijack()
 		ok = true
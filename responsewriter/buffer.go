@@ -2,7 +2,9 @@ package responsewriter
 
 import (
 	"bytes"
+	"io"
 	"net/http"
+	"os"
 
 	"github.com/go-on/stack"
 )
@@ -18,13 +20,19 @@ import (
 // body in the memory which will be inacceptable for large bodies.
 // Therefor Peek is an alternative response writer wrapper that only caching headers and status code
 // but allowing to intercept calls of the Write method.
+//
+// If the body must be buffered, a Threshold can be set (see NewSpillBuffer). A body that exceeds the Threshold
+// is moved to a temporary file. The body should then be read via Reader or WriteTo and the temporary file must be removed
+// via Close or Reset. If there is a Contexter, Close is also registered as its OnFinish hook.
 type Buffer struct {
 
 	// ResponseWriter is the underlying response writer that is wrapped by Buffer
 	http.ResponseWriter
 
-	// if the underlying ResponseWriter is a Contexter, that Contexter is saved here
-	stack.Contexter
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, since Buffer must not pretend to be a Contexter without having one:
+	// pass the Buffer to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 
 	// Buffer is the underlying io.Writer that buffers the response body
 	Buffer bytes.Buffer
@@ -32,6 +40,22 @@ type Buffer struct {
 	// Code is the cached status code
 	Code int
 
+	// Threshold is the number of bytes that are buffered in memory. A body that exceeds it is
+	// moved to a temporary file. If it is 0, the body is always buffered in memory.
+	Threshold int64
+
+	// TempDir is the directory of the temporary file. If it is empty, os.TempDir is used.
+	TempDir string
+
+	// file is the temporary file, if the body exceeded the Threshold
+	file *os.File
+
+	// size is the number of bytes written to file
+	size int64
+
+	// closed tracks if Close has been called
+	closed bool
+
 	// changed tracks if anything has been set on the responsewriter. Also reads from the header
 	// are seen as changes
 	changed bool
@@ -40,22 +64,36 @@ type Buffer struct {
 	header http.Header
 }
 
-// make sure to fulfill the Contexter interface
-var _ stack.Contexter = &Buffer{}
-
 // NewBuffer creates a new Buffer by wrapping the given response writer.
+// If the given response writer is no Contexter, the Contexter field is nil and the Contexter must be
+// retrieved from the request (see stack.ContexterFromRequest).
 func NewBuffer(w http.ResponseWriter) (bf *Buffer) {
 	bf = &Buffer{}
 	bf.ResponseWriter = w
 	if ctx, ok := bf.ResponseWriter.(stack.Contexter); ok {
 		bf.Contexter = ctx
-	} else {
-		panic("no contexter")
 	}
 	bf.header = make(http.Header)
 	return
 }
 
+// NewSpillBuffer creates a new Buffer like NewBuffer that moves the body to a temporary file
+// if it exceeds the given threshold (in bytes)
+func NewSpillBuffer(w http.ResponseWriter, threshold int64) (bf *Buffer) {
+	bf = NewBuffer(w)
+	bf.Threshold = threshold
+	return
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (bf *Buffer) Unwrap() http.ResponseWriter {
+	return bf.ResponseWriter
+}
+
+// Flush does nothing, since the body is buffered until FlushAll is called.
+// It prevents flushing the underlying ResponseWriter when wrapped via stack.WrapResponseWriter.
+func (bf *Buffer) Flush() {}
+
 // Header returns the cached http.Header and tracks this call as change
 func (bf *Buffer) Header() http.Header {
 	bf.changed = true
@@ -68,17 +106,105 @@ func (bf *Buffer) WriteHeader(i int) {
 	bf.Code = i
 }
 
-// Write writes to the underlying buffer and tracks this call as change
+// Write writes to the underlying buffer and tracks this call as change.
+// If the Threshold is exceeded, the body is moved to a temporary file.
+// After Close, Write returns os.ErrClosed.
 func (bf *Buffer) Write(b []byte) (int, error) {
 	bf.changed = true
-	return bf.Buffer.Write(b)
+	if bf.closed {
+		return 0, os.ErrClosed
+	}
+	if bf.file == nil && bf.Threshold > 0 && int64(bf.Buffer.Len()+len(b)) > bf.Threshold {
+		if err := bf.spill(); err != nil {
+			return 0, err
+		}
+	}
+	if bf.file == nil {
+		return bf.Buffer.Write(b)
+	}
+	n, err := bf.file.Write(b)
+	bf.size += int64(n)
+	return n, err
+}
+
+// spill moves the buffered body to a new temporary file
+func (bf *Buffer) spill() error {
+	f, err := os.CreateTemp(bf.TempDir, "stack-buffer-")
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(bf.Buffer.Bytes())
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	bf.file = f
+	bf.size = int64(n)
+	bf.Buffer.Reset()
+	if bf.Contexter != nil {
+		bf.Contexter.OnFinish(func() { bf.Close() })
+	}
+	return nil
+}
+
+// Spilled returns true if the body has been moved to a temporary file
+func (bf *Buffer) Spilled() bool {
+	return bf.file != nil
+}
+
+// Len returns the number of bytes of the buffered body
+func (bf *Buffer) Len() int64 {
+	if bf.file != nil {
+		return bf.size
+	}
+	return int64(bf.Buffer.Len())
+}
+
+// Reader returns a new io.Reader for the buffered body, reading from the start.
+// The reader must not be used after Close or Reset.
+func (bf *Buffer) Reader() io.Reader {
+	if bf.file != nil {
+		return io.NewSectionReader(bf.file, 0, bf.size)
+	}
+	return bytes.NewReader(bf.Buffer.Bytes())
+}
+
+// WriteTo writes the buffered body to the given io.Writer
+func (bf *Buffer) WriteTo(w io.Writer) (int64, error) {
+	if bf.file != nil {
+		return io.Copy(w, bf.Reader())
+	}
+	n, err := w.Write(bf.Buffer.Bytes())
+	return int64(n), err
+}
+
+// Close removes the temporary file, if there is one. Afterwards Write fails until Reset is called.
+func (bf *Buffer) Close() error {
+	bf.closed = true
+	return bf.removeFile()
 }
 
-// Reset set the Buffer to the defaults
+// removeFile closes and removes the temporary file, if there is one
+func (bf *Buffer) removeFile() error {
+	if bf.file == nil {
+		return nil
+	}
+	f := bf.file
+	bf.file = nil
+	bf.size = 0
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Reset set the Buffer to the defaults and removes the temporary file, if there is one.
+// Threshold and TempDir are kept.
 func (bf *Buffer) Reset() {
+	bf.removeFile()
 	bf.Buffer.Reset()
 	bf.Code = 0
 	bf.changed = false
+	bf.closed = false
 	bf.header = make(http.Header)
 }
 
@@ -87,18 +213,24 @@ func (bf *Buffer) FlushAll() {
 	if bf.HasChanged() {
 		bf.FlushHeaders()
 		bf.FlushCode()
-		bf.ResponseWriter.Write(bf.Buffer.Bytes())
+		bf.WriteTo(bf.ResponseWriter)
 	}
 }
 
-// Body returns the bytes of the underlying buffer (that is meant to be the body of the response)
+// Body returns the bytes of the underlying buffer (that is meant to be the body of the response).
+// If the body has been moved to a temporary file, it is read into memory.
 func (bf *Buffer) Body() []byte {
+	if bf.file != nil {
+		b := make([]byte, bf.size)
+		n, _ := bf.file.ReadAt(b, 0)
+		return b[:n]
+	}
 	return bf.Buffer.Bytes()
 }
 
 // BodyString returns the string of the underlying buffer (that is meant to be the body of the response)
 func (bf *Buffer) BodyString() string {
-	return bf.Buffer.String()
+	return string(bf.Body())
 }
 
 // HasChanged returns true if Header, WriteHeader or Write has been called
@@ -0,0 +1,122 @@
+package responsewriter
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestBufferWithoutContexter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	buf := NewBuffer(rec)
+
+	if buf.Contexter != nil {
+		t.Errorf("buf.Contexter should be nil")
+	}
+
+	var wr http.ResponseWriter = buf
+	if _, is := wr.(stack.Contexter); is {
+		t.Errorf("Buffer should not be a Contexter")
+	}
+
+	if _, is := stack.WrapResponseWriter(rec, buf).(stack.Contexter); is {
+		t.Errorf("wrapped Buffer should not be a Contexter")
+	}
+}
+
+// tempFiles returns the number of files inside the given directory
+func tempFiles(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestSpillBuffer(t *testing.T) {
+	dir := t.TempDir()
+	rec := httptest.NewRecorder()
+	buf := NewSpillBuffer(rec, 8)
+	buf.TempDir = dir
+
+	io.WriteString(buf, "12345678")
+	if buf.Spilled() || tempFiles(t, dir) != 0 {
+		t.Fatalf("body within the threshold should stay in memory")
+	}
+
+	io.WriteString(buf, "9")
+	if !buf.Spilled() || tempFiles(t, dir) != 1 {
+		t.Fatalf("body above the threshold should be moved to a file inside TempDir")
+	}
+
+	io.WriteString(buf, "0")
+	if buf.Len() != 10 || buf.Buffer.Len() != 0 {
+		t.Errorf("buf.Len() == %d, in memory %d", buf.Len(), buf.Buffer.Len())
+	}
+
+	if got := buf.BodyString(); got != "1234567890" {
+		t.Errorf("buf.BodyString() == %#v", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		b, _ := io.ReadAll(buf.Reader())
+		if string(b) != "1234567890" {
+			t.Errorf("Reader %d read %#v", i, string(b))
+		}
+	}
+
+	var out bytes.Buffer
+	if n, err := buf.WriteTo(&out); n != 10 || err != nil || out.String() != "1234567890" {
+		t.Errorf("WriteTo wrote %d bytes %#v, error %v", n, out.String(), err)
+	}
+
+	buf.WriteHeader(http.StatusCreated)
+	buf.FlushAll()
+	if rec.Code != http.StatusCreated || rec.Body.String() != "1234567890" {
+		t.Errorf("FlushAll wrote %d %#v", rec.Code, rec.Body.String())
+	}
+
+	if err := buf.Close(); err != nil || tempFiles(t, dir) != 0 {
+		t.Errorf("Close should remove the file, error %v", err)
+	}
+
+	if _, err := io.WriteString(buf, "x"); err != os.ErrClosed {
+		t.Errorf("Write after Close should fail with os.ErrClosed, got %v", err)
+	}
+
+	buf.Reset()
+	if _, err := io.WriteString(buf, strings.Repeat("x", 9)); err != nil || !buf.Spilled() {
+		t.Errorf("Write after Reset should work again, error %v", err)
+	}
+
+	buf.Reset()
+	if buf.Spilled() || buf.Len() != 0 || tempFiles(t, dir) != 0 {
+		t.Errorf("Reset should remove the file")
+	}
+}
+
+func TestSpillBufferOnFinish(t *testing.T) {
+	dir := t.TempDir()
+	stack.New().UseFunc(func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		buf := NewSpillBuffer(wr, 2)
+		buf.TempDir = dir
+		next.ServeHTTP(stack.WrapResponseWriter(wr, buf), req)
+		if !buf.Spilled() {
+			t.Errorf("body should have been moved to a file")
+		}
+		// no Close: the file is removed when the request is finished
+	}).WrapFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		io.WriteString(wr, "large")
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if n := tempFiles(t, dir); n != 0 {
+		t.Errorf("%d temporary files left after the request finished", n)
+	}
+}
@@ -13,13 +13,33 @@ import (
 type Deflate struct {
 	io.WriteCloser
 	http.ResponseWriter
-	stack.Contexter
+
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, so pass the Deflate to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 }
 
 func (w *Deflate) Write(b []byte) (int, error) {
 	return w.WriteCloser.Write(b)
 }
 
+// Unwrap returns the underlying http.ResponseWriter
+func (w *Deflate) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush flushes the pending compressed data and the underlying ResponseWriter if it is a http.Flusher
+func (w *Deflate) Flush() {
+	if fl, ok := w.WriteCloser.(interface {
+		Flush() error
+	}); ok {
+		fl.Flush()
+	}
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
 // if level < 0, default compression is used
 // if level = 0, no compression is used
 // if level >= 9, max compression is used
@@ -4,6 +4,11 @@
 
 /*
 Package responsewriter provides some ResponseWriter wrappers that help with development of middleware
-and also support context sharing via embedding of a stack.Contexter if it is available.
+and also support context sharing if a stack.Contexter is available.
+
+All wrappers implement stack.Unwrapper. To keep the Contexter and the optional interfaces of the wrapped
+http.ResponseWriter (http.Flusher, http.Hijacker etc.), pass them to the next handler via stack.WrapResponseWriter.
+No wrapper is a Contexter itself, so that it never pretends to have one. Wrappers that need the Contexter
+save it in their field Contexter, which is nil if the wrapped http.ResponseWriter is none.
 */
 package responsewriter
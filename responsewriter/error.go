@@ -19,3 +19,21 @@ type ErrCodeFlushedBeforeHeaders struct{}
 func (e ErrCodeFlushedBeforeHeaders) Error() string {
 	return "code flushed before headers"
 }
+
+// ErrHijackNotSupported is the error returned by Recorder.Hijack if the underlying response writer
+// is no http.Hijacker.
+type ErrHijackNotSupported struct{}
+
+// Error returns the error message
+func (e ErrHijackNotSupported) Error() string {
+	return "hijacking not supported by the underlying response writer"
+}
+
+// ErrUnterminatedJS is the error returned by MinifyJS if a string, template, regular expression literal
+// or comment is not terminated.
+type ErrUnterminatedJS struct{}
+
+// Error returns the error message
+func (e ErrUnterminatedJS) Error() string {
+	return "unterminated literal or comment in javascript"
+}
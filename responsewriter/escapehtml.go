@@ -30,13 +30,11 @@ var (
 type EscapeHTML struct {
 	http.ResponseWriter
 
-	// if the underlying ResponseWriter is a Contexter, that Contexter is saved here
-	stack.Contexter
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, so pass the EscapeHTML to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 }
 
-// make sure to fulfill the Contexter interface
-var _ stack.Contexter = &EscapeHTML{}
-
 func NewEscapeHTML(w http.ResponseWriter) *EscapeHTML {
 	e := &EscapeHTML{ResponseWriter: w}
 	if ctx, ok := w.(stack.Contexter); ok {
@@ -45,6 +43,11 @@ func NewEscapeHTML(w http.ResponseWriter) *EscapeHTML {
 	return e
 }
 
+// Unwrap returns the underlying http.ResponseWriter
+func (e *EscapeHTML) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
 // Write writes to the inner *http.ResponseWriter escaping html special chars on the fly
 // Since there is nothing useful to do with the number of bytes written returned from
 // the inner responsewriter, the returned int is always 0. Since there is nothing useful to do
@@ -13,13 +13,33 @@ import (
 type GZip struct {
 	io.WriteCloser
 	http.ResponseWriter
-	stack.Contexter
+
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, so pass the GZip to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 }
 
 func (w *GZip) Write(b []byte) (int, error) {
 	return w.WriteCloser.Write(b)
 }
 
+// Unwrap returns the underlying http.ResponseWriter
+func (w *GZip) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush flushes the pending compressed data and the underlying ResponseWriter if it is a http.Flusher
+func (w *GZip) Flush() {
+	if fl, ok := w.WriteCloser.(interface {
+		Flush() error
+	}); ok {
+		fl.Flush()
+	}
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
 func NewGZIP(rw http.ResponseWriter) *GZip {
 	gz := &GZip{WriteCloser: gzip.NewWriter(rw), ResponseWriter: rw}
 	if ctx, ok := rw.(stack.Contexter); ok {
@@ -14,8 +14,9 @@ type JSONDecoder struct {
 	// ResponseWriter is the underlying response writer that is wrapped by Buffer
 	http.ResponseWriter
 
-	// if the underlying ResponseWriter is a Contexter, that Contexter is saved here
-	stack.Contexter
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, so pass the JSONDecoder to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 
 	// Buffer is the underlying io.Writer that buffers the response body
 	bf bytes.Buffer
@@ -31,22 +32,27 @@ type JSONDecoder struct {
 	header http.Header
 }
 
-// make sure to fulfill the Contexter interface
-var _ stack.Contexter = &JSONDecoder{}
-
 // NewJSONDecoder creates a new JSONDecoder by wrapping the given response writer.
+// If the given response writer is no Contexter, the Contexter field is nil.
 func NewJSONDecoder(w http.ResponseWriter) (dec *JSONDecoder) {
 	dec = &JSONDecoder{}
 	dec.ResponseWriter = w
 	if ctx, ok := dec.ResponseWriter.(stack.Contexter); ok {
 		dec.Contexter = ctx
-	} else {
-		panic("no contexter")
 	}
 	dec.header = make(http.Header)
 	return
 }
 
+// Unwrap returns the underlying http.ResponseWriter
+func (d *JSONDecoder) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// Flush does nothing, since the body is decoded.
+// It prevents flushing the underlying ResponseWriter when wrapped via stack.WrapResponseWriter.
+func (d *JSONDecoder) Flush() {}
+
 // Header returns the cached http.Header and tracks this call as change
 func (d *JSONDecoder) Header() http.Header {
 	d.changed = true
@@ -0,0 +1,336 @@
+package responsewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Minifier returns the minified version of the given body
+type Minifier func(src []byte) ([]byte, error)
+
+// DefaultMinifiers are the Minifiers that are used by NewMinify if no Minifiers are given.
+var DefaultMinifiers = map[string]Minifier{
+	"text/html":              MinifyHTML,
+	"text/css":               MinifyCSS,
+	"application/json":       MinifyJSON,
+	"application/javascript": MinifyJS,
+	"text/javascript":        MinifyJS,
+}
+
+// MinifyJSON compacts the given JSON via json.Compact
+func MinifyJSON(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(src))
+	if err := json.Compact(&buf, src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isSpace reports whether c is whitespace in HTML and CSS
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// cssPunct are the characters of CSS that need no whitespace around them
+const cssPunct = "{};,"
+
+// MinifyCSS removes comments and collapses whitespace of the given CSS.
+// Strings are kept as they are.
+func MinifyCSS(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end == -1 {
+				i = len(src)
+			} else {
+				// i is incremented by the loop
+				i += end + 3
+			}
+			space = true
+			continue
+		case isSpace(c):
+			space = true
+			continue
+		}
+
+		if space && len(out) > 0 && !strings.ContainsRune(cssPunct, rune(out[len(out)-1])) && !strings.ContainsRune(cssPunct, rune(c)) {
+			out = append(out, ' ')
+		}
+		space = false
+
+		if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+			// the last semicolon of a block is not needed
+			out = out[:len(out)-1]
+		}
+
+		if c == '"' || c == '\'' {
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				end = len(src) - 1
+			}
+			out = append(out, src[i:end+1]...)
+			i = end
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// rawElements are the HTML elements whose content is not minified as HTML (see MinifyHTML)
+var rawElements = []string{"pre", "textarea", "script", "style"}
+
+// rawElement returns the name of the raw element that starts at the beginning of b or an empty string
+func rawElement(b []byte) string {
+	for _, name := range rawElements {
+		if len(b) > len(name)+1 && strings.EqualFold(string(b[1:len(name)+1]), name) {
+			if c := b[len(name)+1]; c == '>' || c == '/' || isSpace(c) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// scriptTypeAttr matches the type attribute of a script tag
+var scriptTypeAttr = regexp.MustCompile(`(?i)\stype\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// isJavaScript reports whether the given script tag has no type or a javascript type
+func isJavaScript(tag []byte) bool {
+	m := scriptTypeAttr.FindSubmatch(tag)
+	if m == nil {
+		return true
+	}
+	typ := strings.ToLower(strings.TrimSpace(string(m[1]) + string(m[2]) + string(m[3])))
+	return typ == "" || typ == "module" || strings.Contains(typ, "javascript") || strings.Contains(typ, "ecmascript")
+}
+
+// indexFold returns the index of the first case insensitive occurrence of sub inside b or -1
+func indexFold(b []byte, sub string) int {
+	return bytes.Index(bytes.ToLower(b), []byte(sub))
+}
+
+// MinifyHTML removes comments (except for conditional comments) and collapses whitespace between
+// and inside text of the given HTML to a single space. Tags are kept as they are.
+// The content of pre and textarea elements is kept as it is, the content of style elements is minified via MinifyCSS
+// and the content of script elements via MinifyJS. Scripts of other types (e.g. application/ld+json or templates) and
+// scripts that can't be minified are kept as they are.
+func MinifyHTML(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		if isSpace(c) {
+			space = true
+			i++
+			continue
+		}
+
+		if space && len(out) > 0 && out[len(out)-1] != ' ' {
+			out = append(out, ' ')
+		}
+		space = false
+
+		if c != '<' {
+			out = append(out, c)
+			i++
+			continue
+		}
+
+		// comments
+		if bytes.HasPrefix(src[i:], []byte("<!--")) {
+			end := bytes.Index(src[i+4:], []byte("-->"))
+			if end == -1 {
+				end = len(src)
+			} else {
+				end += i + 7
+			}
+			if bytes.HasPrefix(src[i:], []byte("<!--[if")) {
+				out = append(out, src[i:end]...)
+			}
+			i = end
+			continue
+		}
+
+		// the tag itself, respecting quoted attribute values
+		end := i + 1
+		var quote byte
+		for end < len(src) {
+			ch := src[end]
+			end++
+			if quote != 0 {
+				if ch == quote {
+					quote = 0
+				}
+				continue
+			}
+			if ch == '"' || ch == '\'' {
+				quote = ch
+			} else if ch == '>' {
+				break
+			}
+		}
+		tag := src[i:end]
+		out = append(out, tag...)
+		i = end
+
+		name := rawElement(tag)
+		if name == "" || bytes.HasSuffix(tag, []byte("/>")) {
+			continue
+		}
+
+		// the content of raw elements up to the closing tag
+		close := indexFold(src[i:], "</"+name)
+		if close == -1 {
+			close = len(src) - i
+		}
+		content := src[i : i+close]
+		switch {
+		case name == "style":
+			content, _ = MinifyCSS(content)
+		case name == "script" && isJavaScript(tag):
+			if min, err := MinifyJS(content); err == nil {
+				content = min
+			}
+		}
+		out = append(out, content...)
+		i += close
+	}
+	return out, nil
+}
+
+// Minify is a ResponseWriter wrapper that buffers the bodies of responses with a Content-Type that has a Minifier
+// and writes the minified body on Close. Other responses are passed through. If the minification fails,
+// the body is written as it is. Close must be called after the body has been written.
+//
+// If the next handler sets a Content-Encoding, the body is passed through, since it is already encoded.
+// The Content-Length of minified bodies is removed, since the minified body might still be encoded by an outer
+// wrapper (e.g. GZip). net/http sets it again for bodies that fit into its buffer.
+//
+// A Minify must be passed to the next handler via stack.WrapResponseWriter to keep the Contexter and the
+// optional interfaces of the underlying ResponseWriter.
+type Minify struct {
+	http.ResponseWriter
+
+	// Minifiers maps media types (without parameters) to their Minifier
+	Minifiers map[string]Minifier
+
+	encoding string
+	minifier Minifier
+	buf      bytes.Buffer
+	code     int
+	checked  bool
+	closed   bool
+}
+
+// NewMinify creates a new Minify for the given response writer. If minifiers is nil, DefaultMinifiers is used.
+func NewMinify(rw http.ResponseWriter, minifiers map[string]Minifier) *Minify {
+	if minifiers == nil {
+		minifiers = DefaultMinifiers
+	}
+	return &Minify{ResponseWriter: rw, Minifiers: minifiers, encoding: rw.Header().Get("Content-Encoding")}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (m *Minify) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
+// WriteHeader caches the first status code until the first Write or Close
+func (m *Minify) WriteHeader(code int) {
+	if m.checked && m.minifier == nil {
+		m.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if m.code == 0 {
+		m.code = code
+	}
+}
+
+// check decides if the body is minified. If not, the cached status code is written.
+func (m *Minify) check() {
+	if m.checked {
+		return
+	}
+	m.checked = true
+	header := m.ResponseWriter.Header()
+	if header.Get("Content-Encoding") == m.encoding {
+		mediaType := header.Get("Content-Type")
+		if i := strings.IndexByte(mediaType, ';'); i != -1 {
+			mediaType = mediaType[:i]
+		}
+		m.minifier = m.Minifiers[strings.ToLower(strings.TrimSpace(mediaType))]
+	}
+	if m.minifier == nil && m.code != 0 {
+		m.ResponseWriter.WriteHeader(m.code)
+	}
+}
+
+// Write buffers the body if it is minified, otherwise it writes to the underlying ResponseWriter
+func (m *Minify) Write(b []byte) (int, error) {
+	m.check()
+	if m.minifier == nil {
+		return m.ResponseWriter.Write(b)
+	}
+	return m.buf.Write(b)
+}
+
+// Flush flushes the underlying ResponseWriter if the body is not minified.
+// Otherwise it does nothing, since the body is buffered until Close.
+func (m *Minify) Flush() {
+	m.check()
+	if m.minifier != nil {
+		return
+	}
+	if fl, ok := m.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Close writes the cached status code and the minified body
+func (m *Minify) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if !m.checked && m.code == 0 {
+		// nothing has been written
+		return nil
+	}
+	m.check()
+	if m.minifier == nil {
+		return nil
+	}
+
+	body := m.buf.Bytes()
+	if m.buf.Len() > 0 {
+		if min, err := m.minifier(body); err == nil {
+			body = min
+		}
+	}
+
+	m.ResponseWriter.Header().Del("Content-Length")
+	if m.code != 0 {
+		m.ResponseWriter.WriteHeader(m.code)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	_, err := m.ResponseWriter.Write(body)
+	return err
+}
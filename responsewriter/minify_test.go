@@ -0,0 +1,122 @@
+package responsewriter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"whitespace", "<p>\n  a   b\n</p>\n\n<p>c</p>", "<p> a b </p> <p>c</p>"},
+		{"comments", "<p>a<!-- comment -->b</p>", "<p>ab</p>"},
+		{"conditional comments", "<!--[if IE]>\n  <p>old</p>\n<![endif]--><p>new</p>", "<!--[if IE]>\n  <p>old</p>\n<![endif]--><p>new</p>"},
+		{"quoted attributes", `<a title="a  >  b">  x</a>`, `<a title="a  >  b"> x</a>`},
+		{"pre", "<pre>\n  a\n    b\n</pre>  <p> c </p>", "<pre>\n  a\n    b\n</pre> <p> c </p>"},
+		{"textarea", "<TEXTAREA name=a>  x\n  y</TEXTAREA>", "<TEXTAREA name=a>  x\n  y</TEXTAREA>"},
+		{"script", "<script>\n  var a = 1;  // comment\n  if (a < 2) {}\n</script>", "<script>var a=1;if(a<2){}</script>"},
+		{"module script", "<script type='module'>\n  import a  from 'a'\n</script>", "<script type='module'>import a from'a'</script>"},
+		{"script of other type", `<script type="application/ld+json">  { "a" : 1 }  </script>`, `<script type="application/ld+json">  { "a" : 1 }  </script>`},
+		{"unterminated script", "<script>  a = 'x  </script>", "<script>  a = 'x  </script>"},
+		{"style", "<style>\n  p { color : red; }\n</style>", "<style>p{color : red}</style>"},
+		{"prefix of raw element", "<preview>  a  </preview>", "<preview> a </preview>"},
+	}
+
+	for _, test := range tests {
+		got, err := MinifyHTML([]byte(test.src))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+		}
+		if string(got) != test.expected {
+			t.Errorf("%s: MinifyHTML == %#v != %#v", test.name, string(got), test.expected)
+		}
+	}
+}
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"whitespace", "a  b {\n  color: red;\n  margin: 0 auto;\n}\n", "a b{color: red;margin: 0 auto}"},
+		{"comments", "/* head */a{/* x */b:c}/**/d{}", "a{b:c}d{}"},
+		{"unterminated comment", "a{b:c}/* open", "a{b:c}"},
+		{"strings", `a::after { content: "  /* no comment */  " ; b: '  \'  ' }`, `a::after{content: "  /* no comment */  ";b: '  \'  '}`},
+	}
+
+	for _, test := range tests {
+		got, _ := MinifyCSS([]byte(test.src))
+		if string(got) != test.expected {
+			t.Errorf("%s: MinifyCSS == %#v != %#v", test.name, string(got), test.expected)
+		}
+	}
+}
+
+// serveMinify writes the given body with the given headers through a Minify
+func serveMinify(body string, header ...string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	m := NewMinify(rec, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		m.Header().Set(header[i], header[i+1])
+	}
+	m.WriteHeader(http.StatusCreated)
+	io.WriteString(m, body[:len(body)/2])
+	io.WriteString(m, body[len(body)/2:])
+	m.Close()
+	return rec
+}
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		header  []string
+		minBody string
+	}{
+		{"json", `{ "a" : [ 1, 2 ] }`, []string{"Content-Type", "application/json; charset=utf-8"}, `{"a":[1,2]}`},
+		{"invalid json falls back to the raw body", `{ "a" : `, []string{"Content-Type", "application/json"}, `{ "a" : `},
+		{"html", "<p>  a  </p>", []string{"Content-Type", "text/html", "Content-Length", "12"}, "<p> a </p>"},
+		{"encoded body passes through", "<p>  a  </p>", []string{"Content-Type", "text/html", "Content-Encoding", "br"}, "<p>  a  </p>"},
+		{"javascript", "var a  =  1; // one", []string{"Content-Type", "text/javascript; charset=utf-8"}, "var a=1;"},
+		{"invalid javascript falls back to the raw body", "var a  = 'x", []string{"Content-Type", "application/javascript"}, "var a  = 'x"},
+	}
+
+	for _, test := range tests {
+		rec := serveMinify(test.body, test.header...)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("%s: rec.Code == %d != %d", test.name, rec.Code, http.StatusCreated)
+		}
+
+		if got := rec.Body.String(); got != test.minBody {
+			t.Errorf("%s: body == %#v != %#v", test.name, got, test.minBody)
+		}
+
+		if got := rec.Header().Get("Content-Length"); got == "12" {
+			t.Errorf("%s: Content-Length of the minified body should be removed", test.name)
+		}
+	}
+}
+
+func TestMinifyNoBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	m := NewMinify(rec, nil)
+	m.Header().Set("Content-Type", "text/html")
+	m.WriteHeader(http.StatusNotModified)
+	m.Close()
+
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("response without body got %d %#v", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMinifyContexter(t *testing.T) {
+	var wr http.ResponseWriter = NewMinify(httptest.NewRecorder(), nil)
+	if _, is := wr.(stack.Contexter); is {
+		t.Errorf("Minify without Contexter should not be a Contexter")
+	}
+}
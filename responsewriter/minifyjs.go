@@ -0,0 +1,224 @@
+package responsewriter
+
+import (
+	"strings"
+)
+
+// isIdent reports whether c may be part of a javascript identifier, keyword or number
+func isIdent(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '\\' || c >= 0x80
+}
+
+// jsNoASIBefore are the characters a javascript expression can't end with, so that a line break
+// after them never leads to an automatic semicolon insertion
+const jsNoASIBefore = "{([,;:=?&|!~*%^<>"
+
+// jsNoASIAfter are the characters that continue a javascript statement, so that a line break
+// before them never leads to an automatic semicolon insertion
+const jsNoASIAfter = "})],;:.?=*%^&|<>"
+
+// jsRegexpAfter are the keywords after which a slash starts a regular expression literal
+var jsRegexpAfter = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+	"void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+// regexpAllowed reports whether a slash after the given minified javascript starts a regular expression literal.
+// A slash after an identifier, a number, a literal, ) or ] is a division. So is a slash after }, although
+// it might end a block.
+func regexpAllowed(out []byte) bool {
+	if len(out) == 0 {
+		return true
+	}
+	last := out[len(out)-1]
+	switch {
+	case last == ')' || last == ']' || last == '}' || last == '"' || last == '\'' || last == '`':
+		return false
+	case isIdent(last):
+		start := len(out)
+		for start > 0 && isIdent(out[start-1]) {
+			start--
+		}
+		return jsRegexpAfter[string(out[start:])]
+	}
+	return true
+}
+
+// skipString returns the index after the string literal that starts at src[i] with a quote
+func skipString(src []byte, i int) (int, error) {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if i+2 < len(src) && src[i+1] == '\r' && src[i+2] == '\n' {
+				// line continuation
+				i++
+			}
+			i++
+		case quote:
+			return i + 1, nil
+		case '\n', '\r':
+			return 0, ErrUnterminatedJS{}
+		}
+	}
+	return 0, ErrUnterminatedJS{}
+}
+
+// skipRegexp returns the index after the body of the regular expression literal that starts at src[i]
+// with a slash. The flags are handled like identifiers.
+func skipRegexp(src []byte, i int) (int, error) {
+	class := false
+	for i++; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\\':
+			i++
+		case c == '\n' || c == '\r':
+			return 0, ErrUnterminatedJS{}
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '/' && !class:
+			return i + 1, nil
+		}
+	}
+	return 0, ErrUnterminatedJS{}
+}
+
+// skipTemplate returns the index after the template literal that starts at src[i] with a backtick.
+// The expressions inside ${} may contain strings, templates and comments.
+func skipTemplate(src []byte, i int) (int, error) {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, nil
+		case '$':
+			if i+1 < len(src) && src[i+1] == '{' {
+				end, err := skipExpression(src, i+2)
+				if err != nil {
+					return 0, err
+				}
+				// i is incremented by the loop
+				i = end - 1
+			}
+		}
+	}
+	return 0, ErrUnterminatedJS{}
+}
+
+// skipExpression returns the index after the closing brace of the template expression that starts at src[i]
+func skipExpression(src []byte, i int) (int, error) {
+	depth := 0
+	for i < len(src) {
+		var err error
+		switch c := src[i]; {
+		case c == '"' || c == '\'':
+			i, err = skipString(src, i)
+		case c == '`':
+			i, err = skipTemplate(src, i)
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			i, _, err = skipComment(src, i)
+		case c == '{':
+			depth++
+			i++
+		case c == '}':
+			if depth == 0 {
+				return i + 1, nil
+			}
+			depth--
+			i++
+		default:
+			i++
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, ErrUnterminatedJS{}
+}
+
+// skipComment returns the index after the comment that starts at src[i] and whether it contains a line break.
+// Line comments end with their line break, which is not skipped.
+func skipComment(src []byte, i int) (end int, newline bool, err error) {
+	if src[i+1] == '/' {
+		end = i + 2
+		for end < len(src) && src[end] != '\n' && src[end] != '\r' {
+			end++
+		}
+		return end, false, nil
+	}
+	idx := strings.Index(string(src[i+2:]), "*/")
+	if idx == -1 {
+		return 0, false, ErrUnterminatedJS{}
+	}
+	end = i + 2 + idx + 2
+	return end, strings.ContainsAny(string(src[i+2:end-2]), "\n\r"), nil
+}
+
+// MinifyJS removes comments and whitespace of the given javascript. String, template and regular expression
+// literals are kept as they are.
+//
+// It is conservative: whitespace is only removed where it separates no tokens, and line breaks are only removed
+// where they can't lead to an automatic semicolon insertion. A slash after an identifier, a number, a literal, ), ]
+// or } is taken as division, otherwise (also after keywords like return) as start of a regular expression literal.
+// If a literal or comment is not terminated, ErrUnterminatedJS is returned.
+func MinifyJS(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	space, newline := false, false
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == '\n' || c == '\r':
+			newline = true
+			i++
+			continue
+		case isSpace(c) || c == '\v':
+			space = true
+			i++
+			continue
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			end, nl, err := skipComment(src, i)
+			if err != nil {
+				return nil, err
+			}
+			space = true
+			newline = newline || nl
+			i = end
+			continue
+		}
+
+		if (space || newline) && len(out) > 0 {
+			last := out[len(out)-1]
+			switch {
+			case newline && !strings.ContainsRune(jsNoASIBefore, rune(last)) && !strings.ContainsRune(jsNoASIAfter, rune(c)):
+				out = append(out, '\n')
+			case isIdent(last) && isIdent(c),
+				// a + +b, a - -b, a / /re/ and 1 .toString()
+				last == c && (c == '+' || c == '-' || c == '/'),
+				last >= '0' && last <= '9' && c == '.':
+				out = append(out, ' ')
+			}
+		}
+		space, newline = false, false
+
+		end := i + 1
+		var err error
+		switch {
+		case c == '"' || c == '\'':
+			end, err = skipString(src, i)
+		case c == '`':
+			end, err = skipTemplate(src, i)
+		case c == '/' && regexpAllowed(out):
+			end, err = skipRegexp(src, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, src[i:end]...)
+		i = end
+	}
+	return out, nil
+}
@@ -0,0 +1,41 @@
+package responsewriter
+
+import (
+	"testing"
+)
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"whitespace", "var  a = 1 ;\n\nfunction f ( x ) {\n  return x * 2 ;\n}\n", "var a=1;function f(x){return x*2;}"},
+		{"comments", "a = 1; // one\n/* two\n */ b = 2; /**/ c = a/**/+b", "a=1;b=2;c=a+b"},
+		{"line breaks for semicolon insertion", "a = b\nc = d\nreturn\nx\ni\n++j\nf()\n[1].x", "a=b\nc=d\nreturn\nx\ni\n++j\nf()\n[1].x"},
+		{"line breaks that can be removed", "a = {\n  b: 1,\n  c: 2\n}\nx\n.y()", "a={b:1,c:2}\nx.y()"},
+		{"separating spaces", "a + +b - -c; var x = 1 .toString(); typeof x", "a+ +b- -c;var x=1 .toString();typeof x"},
+		{"strings", `a = "  // no comment  " + ' /* \'  */ '`, `a="  // no comment  "+' /* \'  */ '`},
+		{"line continuation", "a = 'x \\\n  y'", "a='x \\\n  y'"},
+		{"templates", "a = `  ${ b + `  ${ c }  ` }  // ${ \"}\" }  `", "a=`  ${ b + `  ${ c }  ` }  // ${ \"}\" }  `"},
+		{"regexp literals", "a = / +\\/ [/ ]/g.test(s); if (x) return /  \\/\\//.exec(y)", "a=/ +\\/ [/ ]/g.test(s);if(x)return/  \\/\\//.exec(y)"},
+		{"division", "a = b / 2 / c; d = (e) / f; g = h[0] / 1", "a=b/2/c;d=(e)/f;g=h[0]/1"},
+		{"regexp after division", "a = b / /x/.source.length", "a=b/ /x/.source.length"},
+	}
+
+	for _, test := range tests {
+		got, err := MinifyJS([]byte(test.src))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+		}
+		if string(got) != test.expected {
+			t.Errorf("%s: MinifyJS == %#v != %#v", test.name, string(got), test.expected)
+		}
+	}
+}
+
+func TestMinifyJSUnterminated(t *testing.T) {
+	for _, src := range []string{`a = "x`, "a = 'x\ny'", "a = `x ${ y `", "a = /x\n/", "a /* x"} {
+		if _, err := MinifyJS([]byte(src)); err != (ErrUnterminatedJS{}) {
+			t.Errorf("MinifyJS(%#v) returned error %v, expected ErrUnterminatedJS", src, err)
+		}
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"net/http"
 )
 
-// PanicCodes is a debugging tool to get a stack trace, if some http.StatusCode is set to one of its codes
+// PanicCodes is a debugging tool to get a stack trace, if some http.StatusCode is set to one of its codes.
+// For large bodies, a Threshold may be set (see NewSpillBuffer); the temporary file is removed via Close.
 type PanicCodes struct {
 	// Codes that should trigger a panic
 	Codes []int
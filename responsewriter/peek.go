@@ -19,8 +19,9 @@ type Peek struct {
 	// the underlying response writer
 	http.ResponseWriter
 
-	// if the underlying ResponseWriter is a Contexter, that Contexter is saved here
-	stack.Contexter
+	// Contexter is the Contexter of the underlying ResponseWriter, if it is one.
+	// It is not embedded, so pass the Peek to the next handler via stack.WrapResponseWriter.
+	Contexter stack.Contexter
 
 	changed        bool
 	header         http.Header
@@ -37,9 +38,6 @@ type Peek struct {
 	proceed func(*Peek) bool
 }
 
-// make sure to fulfill the Contexter interface
-var _ stack.Contexter = &Peek{}
-
 // NewPeek creates a new Peek for the given response writer using the given proceed function.
 //
 // The proceed function is called when the Write method is run for the first time.
@@ -85,6 +83,22 @@ func (p *Peek) FlushMissing() {
 	p.FlushCode()
 }
 
+// Unwrap returns the underlying http.ResponseWriter
+func (p *Peek) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// Flush flushes the underlying ResponseWriter if it is a http.Flusher and the body is written to it.
+// Before that, the cached headers and status code are not flushed.
+func (p *Peek) Flush() {
+	if !p.bodyWritten {
+		return
+	}
+	if fl, ok := p.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
 // Header returns the cached http.Header, tracking the call as change
 func (p *Peek) Header() http.Header {
 	p.changed = true
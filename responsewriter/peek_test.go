@@ -0,0 +1,60 @@
+package responsewriter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestContexterField(t *testing.T) {
+	wrappers := map[string]func(http.ResponseWriter) (http.ResponseWriter, stack.Contexter){
+		"Peek": func(wr http.ResponseWriter) (http.ResponseWriter, stack.Contexter) {
+			pk := NewPeek(wr, nil)
+			return pk, pk.Contexter
+		},
+		"GZip": func(wr http.ResponseWriter) (http.ResponseWriter, stack.Contexter) {
+			gz := NewGZIP(wr)
+			return gz, gz.Contexter
+		},
+		"Deflate": func(wr http.ResponseWriter) (http.ResponseWriter, stack.Contexter) {
+			df := NewDeflate(wr, 6)
+			return df, df.Contexter
+		},
+		"EscapeHTML": func(wr http.ResponseWriter) (http.ResponseWriter, stack.Contexter) {
+			e := NewEscapeHTML(wr)
+			return e, e.Contexter
+		},
+		"JSONDecoder": func(wr http.ResponseWriter) (http.ResponseWriter, stack.Contexter) {
+			dec := NewJSONDecoder(wr)
+			return dec, dec.Contexter
+		},
+	}
+
+	for name, wrap := range wrappers {
+		rec := httptest.NewRecorder()
+		wr, ctx := wrap(rec)
+		if ctx != nil {
+			t.Errorf("%s: Contexter should be nil", name)
+		}
+		if _, is := wr.(stack.Contexter); is {
+			t.Errorf("%s should not be a Contexter", name)
+		}
+		if _, is := stack.WrapResponseWriter(rec, wr).(stack.Contexter); is {
+			t.Errorf("wrapped %s should not be a Contexter", name)
+		}
+
+		stack.New().UseFunc(func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+			inner, ctx := wrap(wr)
+			if ctx == nil {
+				t.Errorf("%s: Contexter should be set", name)
+			}
+			next.ServeHTTP(stack.WrapResponseWriter(wr, inner), req)
+		}).WrapFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+			if _, is := wr.(stack.Contexter); !is {
+				t.Errorf("wrapped %s should keep the Contexter", name)
+			}
+		}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+}
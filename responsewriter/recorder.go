@@ -0,0 +1,137 @@
+package responsewriter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Recorder is a ResponseWriter wrapper that passes everything through to the underlying ResponseWriter
+// while recording what was actually sent: the final status code, the number of bytes written,
+// the time to first byte and whether the headers were flushed or the connection was hijacked.
+//
+// In contrast to Buffer and Peek, nothing is cached, so it is cheap enough to be used by logging,
+// metrics and tracing middleware for every request. It must be passed to the next handler via
+// stack.WrapResponseWriter to keep the Contexter and the optional interfaces of the underlying ResponseWriter.
+type Recorder struct {
+	// the underlying response writer
+	http.ResponseWriter
+
+	start         time.Time
+	firstByte     time.Time
+	code          int
+	bytes         int64
+	headerWritten bool
+	hijacked      bool
+}
+
+// NewRecorder creates a new Recorder for the given response writer.
+// The time to first byte is measured from the creation of the Recorder.
+func NewRecorder(rw http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: rw, start: time.Now()}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the final status code that has been sent, including the implicit
+// http.StatusOK of a Write without WriteHeader. It returns 0 if nothing has been sent yet.
+func (r *Recorder) Status() int {
+	return r.code
+}
+
+// BytesWritten returns the number of body bytes that have been written to the underlying ResponseWriter
+func (r *Recorder) BytesWritten() int64 {
+	return r.bytes
+}
+
+// TimeToFirstByte returns the time between the creation of the Recorder and the flushing of the headers.
+// It returns 0 if the headers have not been flushed yet.
+func (r *Recorder) TimeToFirstByte() time.Duration {
+	if r.firstByte.IsZero() {
+		return 0
+	}
+	return r.firstByte.Sub(r.start)
+}
+
+// HeaderWritten returns true if the headers have been flushed to the underlying ResponseWriter
+func (r *Recorder) HeaderWritten() bool {
+	return r.headerWritten
+}
+
+// Hijacked returns true if the connection has been hijacked via Hijack
+func (r *Recorder) Hijacked() bool {
+	return r.hijacked
+}
+
+// writeHeader records the given status code as final status code, if there is none yet.
+// Informational status codes (1xx) are not final, except for http.StatusSwitchingProtocols.
+func (r *Recorder) writeHeader(code int) {
+	if r.headerWritten {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+	r.code = code
+	r.headerWritten = true
+	r.firstByte = time.Now()
+}
+
+// WriteHeader writes the status code to the underlying ResponseWriter and records it
+func (r *Recorder) WriteHeader(code int) {
+	r.writeHeader(code)
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes to the underlying ResponseWriter and records the written bytes
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.writeHeader(http.StatusOK)
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom copies from the given reader to the underlying ResponseWriter, using its io.ReaderFrom
+// implementation if there is one, and records the written bytes
+func (r *Recorder) ReadFrom(src io.Reader) (int64, error) {
+	r.writeHeader(http.StatusOK)
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{r.ResponseWriter}, src)
+	}
+	r.bytes += n
+	return n, err
+}
+
+// Flush flushes the underlying ResponseWriter if it is a http.Flusher.
+// Since this sends the headers, the status code is recorded as http.StatusOK, if there is none yet.
+func (r *Recorder) Flush() {
+	if fl, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.writeHeader(http.StatusOK)
+		fl.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter and records it.
+// If the underlying ResponseWriter is no http.Hijacker, ErrHijackNotSupported is returned.
+func (r *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackNotSupported{}
+	}
+	c, brw, err := hj.Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return c, brw, err
+}
@@ -0,0 +1,55 @@
+package responsewriter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := NewRecorder(rec)
+
+	if r.Status() != 0 || r.HeaderWritten() {
+		t.Errorf("nothing should be recorded yet")
+	}
+
+	io.WriteString(r, "hello ")
+	io.Copy(r, strings.NewReader("world"))
+	r.WriteHeader(http.StatusNotFound)
+
+	if r.Status() != http.StatusOK {
+		t.Errorf("r.Status() == %d != %d", r.Status(), http.StatusOK)
+	}
+
+	if r.BytesWritten() != 11 || rec.Body.String() != "hello world" {
+		t.Errorf("r.BytesWritten() == %d, body %#v", r.BytesWritten(), rec.Body.String())
+	}
+
+	if _, _, err := r.Hijack(); err != (ErrHijackNotSupported{}) {
+		t.Errorf("expected ErrHijackNotSupported, got %v", err)
+	}
+
+	if r.Hijacked() {
+		t.Errorf("r.Hijacked() should be false")
+	}
+}
+
+func TestRecorderContexter(t *testing.T) {
+	var wr http.ResponseWriter = NewRecorder(httptest.NewRecorder())
+	if _, is := wr.(stack.Contexter); is {
+		t.Errorf("Recorder without Contexter should not be a Contexter")
+	}
+
+	stack.New().UseFunc(func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		next.ServeHTTP(stack.WrapResponseWriter(wr, NewRecorder(wr)), req)
+	}).WrapFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		if _, is := wr.(stack.Contexter); !is {
+			t.Errorf("wrapped Recorder should keep the Contexter")
+		}
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
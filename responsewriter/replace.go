@@ -0,0 +1,322 @@
+package responsewriter
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Replacement is a streaming replacement that is applied by a Replacer.
+// A Replacement holds state and must not be shared between Replacers; create one per response.
+type Replacement struct {
+	// find returns the indexes of the non overlapping matches inside b (see regexp.Regexp.FindAllSubmatchIndex)
+	find func(b []byte) [][]int
+
+	// expand appends the replacement of the match at the given indexes of b to dst
+	expand func(dst, b []byte, match []int) []byte
+
+	// maxLen is the maximum length of a match
+	maxLen int
+
+	// limit is the maximum number of replacements, 0 means no limit
+	limit int
+
+	// count is the number of replacements so far
+	count int
+
+	// fallback is appended to the end, if there was no match
+	fallback []byte
+
+	// pending holds the bytes that might be the start of a match
+	pending []byte
+}
+
+// StringReplacement returns a Replacement that replaces all occurrences of old by new.
+// If old is empty, nothing is replaced.
+func StringReplacement(old, new string) *Replacement {
+	return BytesReplacement([]byte(old), []byte(new))
+}
+
+// BytesReplacement returns a Replacement that replaces all occurrences of old by new.
+// If old is empty, nothing is replaced.
+func BytesReplacement(old, new []byte) *Replacement {
+	return &Replacement{
+		maxLen: len(old),
+		find: func(b []byte) (matches [][]int) {
+			if len(old) == 0 {
+				return nil
+			}
+			for start := 0; ; {
+				i := bytes.Index(b[start:], old)
+				if i == -1 {
+					return
+				}
+				matches = append(matches, []int{start + i, start + i + len(old)})
+				start += i + len(old)
+			}
+		},
+		expand: func(dst, b []byte, match []int) []byte {
+			return append(dst, new...)
+		},
+	}
+}
+
+// RegexpReplacement returns a Replacement that replaces all matches of re by template,
+// which may refer to submatches like regexp.Regexp.Expand.
+//
+// Since the body is streamed, maxLen must be the maximum length of a match; longer matches are not found,
+// so nothing is replaced if maxLen is smaller than 1.
+// Empty matches are ignored and ^, $ and \b may also match at the boundaries of the bytes that are held back.
+func RegexpReplacement(re *regexp.Regexp, template string, maxLen int) *Replacement {
+	tmpl := []byte(template)
+	return &Replacement{
+		maxLen: maxLen,
+		find: func(b []byte) (matches [][]int) {
+			for _, m := range re.FindAllSubmatchIndex(b, -1) {
+				if m[1] > m[0] && m[1]-m[0] <= maxLen {
+					matches = append(matches, m)
+				}
+			}
+			return
+		},
+		expand: func(dst, b []byte, match []int) []byte {
+			return re.Expand(dst, tmpl, b, match)
+		},
+	}
+}
+
+// InjectBefore returns a Replacement that inserts the snippet before the first closing tag of the given
+// HTML element (case insensitive), e.g. before </head> or </body>. If there is no such tag, the snippet is appended.
+func InjectBefore(element string, snippet []byte) *Replacement {
+	re := regexp.MustCompile(`(?i)</` + regexp.QuoteMeta(element) + `\s*>`)
+	maxLen := len(element) + 16
+	return &Replacement{
+		maxLen:   maxLen,
+		limit:    1,
+		fallback: snippet,
+		find: func(b []byte) (matches [][]int) {
+			for _, m := range re.FindAllIndex(b, -1) {
+				if m[1]-m[0] <= maxLen {
+					matches = append(matches, m)
+				}
+			}
+			return
+		},
+		expand: func(dst, b []byte, match []int) []byte {
+			dst = append(dst, snippet...)
+			return append(dst, b[match[0]:match[1]]...)
+		},
+	}
+}
+
+// apply applies the replacement to the pending bytes plus b and returns the bytes that are final.
+// If final is true, nothing is held back.
+func (r *Replacement) apply(b []byte, final bool) []byte {
+	buf := append(r.pending, b...)
+	r.pending = nil
+
+	if r.limit > 0 && r.count >= r.limit {
+		return buf
+	}
+
+	// a match starting at or after hold might be incomplete
+	hold := len(buf)
+	if !final {
+		hold = len(buf) - r.maxLen + 1
+		if hold < 0 {
+			hold = 0
+		}
+		if hold > len(buf) {
+			// maxLen < 1: there is nothing to hold back, since nothing matches
+			hold = len(buf)
+		}
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range r.find(buf) {
+		if m[0] >= hold {
+			break
+		}
+		out = append(out, buf[last:m[0]]...)
+		out = r.expand(out, buf, m)
+		last = m[1]
+		r.count++
+		if r.limit > 0 && r.count >= r.limit {
+			return append(out, buf[last:]...)
+		}
+	}
+
+	if last < hold {
+		out = append(out, buf[last:hold]...)
+		last = hold
+	}
+	r.pending = append(r.pending, buf[last:]...)
+	if final && r.count == 0 {
+		out = append(out, r.fallback...)
+	}
+	return out
+}
+
+// Replacer is a ResponseWriter wrapper that applies a chain of Replacements to the body while it is streamed.
+// Matches that are split across Write calls are found, since the bytes that might be the start of a match are held
+// back until the next Write or Close. Close must be called after the body has been written.
+//
+// The Replacements are only applied if the Content-Type of the response starts with one of the ContentTypes
+// (or if there are no ContentTypes). In this case the Content-Length header is removed, since the length changes.
+// Therefor the status code is held back until the first Write or Close.
+// If there are ContentTypes and the response has no Content-Type header, it is detected from the bytes of the
+// first Write via http.DetectContentType, as net/http would do.
+//
+// If the next handler sets a Content-Encoding, the body is passed through, since it is already encoded.
+//
+// A Replacer must be passed to the next handler via stack.WrapResponseWriter to keep the Contexter and the
+// optional interfaces of the underlying ResponseWriter.
+type Replacer struct {
+	http.ResponseWriter
+
+	// ContentTypes restricts the replacements to responses with one of the content types (prefixes)
+	ContentTypes []string
+
+	// Replacements are applied in order, each one to the output of the previous one
+	Replacements []*Replacement
+
+	// prepare is called before the replacements are applied for the first time
+	prepare func()
+
+	// encoding is the Content-Encoding at creation, e.g. set by an outer GZip
+	encoding string
+
+	code    int
+	checked bool
+	active  bool
+	written bool
+	closed  bool
+}
+
+// NewReplacer creates a new Replacer that applies the given replacements to responses with
+// one of the given content types (prefixes). If contentTypes is empty, all responses are affected.
+func NewReplacer(rw http.ResponseWriter, contentTypes []string, replacements ...*Replacement) *Replacer {
+	return &Replacer{ResponseWriter: rw, ContentTypes: contentTypes, Replacements: replacements, encoding: rw.Header().Get("Content-Encoding")}
+}
+
+// NewHTMLInjector creates a new Replacer that injects HTML snippets into text/html responses before
+// the closing </head> and </body> tags (see InjectBefore). Other responses are left untouched.
+// Responses without Content-Type are sniffed (see Replacer).
+//
+// The snippets function is called when the first byte of a text/html response is written, so that the
+// snippets may depend on values that are set by the next handlers (e.g. a CSP nonce). Empty snippets are skipped.
+func NewHTMLInjector(rw http.ResponseWriter, snippets func() (head, body []byte)) *Replacer {
+	r := NewReplacer(rw, []string{"text/html"})
+	r.prepare = func() {
+		head, body := snippets()
+		if len(head) > 0 {
+			r.Replacements = append(r.Replacements, InjectBefore("head", head))
+		}
+		if len(body) > 0 {
+			r.Replacements = append(r.Replacements, InjectBefore("body", body))
+		}
+	}
+	return r
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (r *Replacer) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// WriteHeader caches the status code until the first Write or Close
+func (r *Replacer) WriteHeader(code int) {
+	if r.checked {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	r.code = code
+}
+
+// check decides if the replacements are applied and writes the cached status code.
+// b are the bytes of the first Write, if any.
+func (r *Replacer) check(b []byte) {
+	if r.checked {
+		return
+	}
+	r.checked = true
+	header := r.ResponseWriter.Header()
+	if header.Get("Content-Encoding") == r.encoding {
+		r.active = len(r.ContentTypes) == 0
+		if _, has := header["Content-Type"]; !has && len(b) > 0 && len(r.ContentTypes) > 0 {
+			// net/http would sniff the same bytes, but only after the decision
+			header.Set("Content-Type", http.DetectContentType(b))
+		}
+		ctype := header.Get("Content-Type")
+		for _, ct := range r.ContentTypes {
+			if strings.HasPrefix(ctype, ct) {
+				r.active = true
+				break
+			}
+		}
+	}
+	if r.active {
+		header.Del("Content-Length")
+		if r.prepare != nil {
+			r.prepare()
+		}
+	}
+	if r.code != 0 {
+		r.ResponseWriter.WriteHeader(r.code)
+	}
+}
+
+// Write applies the replacements and writes the final bytes to the underlying ResponseWriter.
+// It returns len(b) if there was no error, since bytes might be held back.
+func (r *Replacer) Write(b []byte) (int, error) {
+	r.check(b)
+	if !r.active {
+		return r.ResponseWriter.Write(b)
+	}
+	r.written = true
+	if _, err := r.write(b, false); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// write runs b through the chain of replacements and writes the result
+func (r *Replacer) write(b []byte, final bool) (int, error) {
+	for _, repl := range r.Replacements {
+		b = repl.apply(b, final)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush writes the cached status code and flushes the underlying ResponseWriter if it is a http.Flusher.
+// Bytes that might be the start of a match are still held back.
+func (r *Replacer) Flush() {
+	r.check(nil)
+	if fl, ok := r.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Close writes the cached status code and the bytes that have been held back
+func (r *Replacer) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if !r.checked && r.code == 0 {
+		// nothing has been written
+		return nil
+	}
+	r.check(nil)
+	if !r.active || !r.written {
+		// responses without body (e.g. 304 Not Modified) get no fallbacks
+		return nil
+	}
+	_, err := r.write(nil, true)
+	return err
+}
@@ -0,0 +1,244 @@
+package responsewriter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-on/stack"
+)
+
+func TestReplacer(t *testing.T) {
+	mail := regexp.MustCompile(`(\w+)@example\.com`)
+
+	tests := []struct {
+		name         string
+		contentTypes []string
+		contentType  string
+		replacements func() []*Replacement
+		chunks       []string
+		body         string
+	}{
+		{
+			name:         "match split across writes",
+			replacements: func() []*Replacement { return []*Replacement{StringReplacement("world", "there")} },
+			chunks:       []string{"hello wo", "r", "ld and world"},
+			body:         "hello there and there",
+		},
+		{
+			name: "chained replacements",
+			replacements: func() []*Replacement {
+				return []*Replacement{StringReplacement("a", "b"), StringReplacement("b", "c")}
+			},
+			chunks: []string{"ab", "ba"},
+			body:   "cccc",
+		},
+		{
+			name: "regexp submatch expansion",
+			replacements: func() []*Replacement {
+				return []*Replacement{RegexpReplacement(mail, "$1 at example", 32)}
+			},
+			chunks: []string{"mail bob@exa", "mple.com or al", "ice@example.com"},
+			body:   "mail bob at example or alice at example",
+		},
+		{
+			name: "empty old string and zero maxLen",
+			replacements: func() []*Replacement {
+				return []*Replacement{StringReplacement("", "x"), RegexpReplacement(mail, "y", 0), BytesReplacement(nil, nil)}
+			},
+			chunks: []string{"bob@example.com", " stays"},
+			body:   "bob@example.com stays",
+		},
+		{
+			name: "limit and fallback of InjectBefore",
+			replacements: func() []*Replacement {
+				return []*Replacement{InjectBefore("head", []byte("<script>")), InjectBefore("body", []byte("<footer>"))}
+			},
+			chunks: []string{"<html><head></he", "ad><HEAD></head>"},
+			body:   "<html><head><script></head><HEAD></head><footer>",
+		},
+		{
+			name:         "matching content type",
+			contentTypes: []string{"text/html"},
+			contentType:  "text/html; charset=utf-8",
+			replacements: func() []*Replacement { return []*Replacement{StringReplacement("a", "b")} },
+			chunks:       []string{"aa"},
+			body:         "bb",
+		},
+		{
+			name:         "other content type passes through",
+			contentTypes: []string{"text/html"},
+			contentType:  "application/json",
+			replacements: func() []*Replacement { return []*Replacement{StringReplacement("a", "b")} },
+			chunks:       []string{"aa"},
+			body:         "aa",
+		},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		r := NewReplacer(rec, test.contentTypes, test.replacements()...)
+		if test.contentType != "" {
+			r.Header().Set("Content-Type", test.contentType)
+		}
+		for _, chunk := range test.chunks {
+			if n, err := io.WriteString(r, chunk); n != len(chunk) || err != nil {
+				t.Errorf("%s: Write returned %d, %v", test.name, n, err)
+			}
+		}
+		r.Close()
+
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("%s: body == %#v != %#v", test.name, got, test.body)
+		}
+	}
+}
+
+func TestReplacerHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := NewReplacer(rec, []string{"text/"}, StringReplacement("a", "bb"))
+	r.Header().Set("Content-Length", "2")
+	r.WriteHeader(http.StatusCreated)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code should be held back until the first Write, got %d", rec.Code)
+	}
+
+	r.Header().Set("Content-Type", "text/plain")
+	io.WriteString(r, "aa")
+	r.Close()
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("rec.Code == %d != %d", rec.Code, http.StatusCreated)
+	}
+
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length should be removed, got %#v", got)
+	}
+
+	if got := rec.Body.String(); got != "bbbb" {
+		t.Errorf("body == %#v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	r = NewReplacer(rec, []string{"text/"}, StringReplacement("a", "bb"))
+	r.Header().Set("Content-Length", "2")
+	r.Header().Set("Content-Type", "image/png")
+	io.WriteString(r, "aa")
+	r.Close()
+
+	if got := rec.Header().Get("Content-Length"); got != "2" {
+		t.Errorf("Content-Length of other content types should be kept, got %#v", got)
+	}
+}
+
+func TestReplacerFlushAndClose(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := NewReplacer(rec, nil, StringReplacement("world", "there"))
+	r.WriteHeader(http.StatusAccepted)
+	r.Flush()
+
+	if !rec.Flushed || rec.Code != http.StatusAccepted {
+		t.Errorf("Flush should write the status code and flush, got %d %v", rec.Code, rec.Flushed)
+	}
+
+	io.WriteString(r, "hello wor")
+	r.Flush()
+	if got := rec.Body.String(); got != "hello" {
+		t.Errorf("Flush should hold back a possible match, body is %#v", got)
+	}
+
+	r.Close()
+	r.Close()
+	if got := rec.Body.String(); got != "hello wor" {
+		t.Errorf("Close should write the held back bytes once, body is %#v", got)
+	}
+
+	// no body, no fallback
+	rec = httptest.NewRecorder()
+	r = NewReplacer(rec, nil, InjectBefore("body", []byte("<footer>")))
+	r.WriteHeader(http.StatusNotModified)
+	r.Close()
+
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("response without body got %d %#v", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReplacerContexter(t *testing.T) {
+	var wr http.ResponseWriter = NewReplacer(httptest.NewRecorder(), nil)
+	if _, is := wr.(stack.Contexter); is {
+		t.Errorf("Replacer without Contexter should not be a Contexter")
+	}
+}
+
+func TestHTMLInjector(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		chunks      []string
+		body        string
+	}{
+		{"both tags", "text/html", []string{"<html><head></head><body></body></html>"}, "<html><head>H</head><body>B</body></html>"},
+		{"case and whitespace", "text/html; charset=utf-8", []string{"<HEAD></HEAD >", "<BODY></Body\n>"}, "<HEAD>H</HEAD ><BODY>B</Body\n>"},
+		{"split across writes", "text/html", []string{"<head></h", "e", "ad><body></bo", "dy", ">"}, "<head>H</head><body>B</body>"},
+		{"first tag only", "text/html", []string{"</head></head></body></body>"}, "H</head></head>B</body></body>"},
+		{"fallback", "text/html", []string{"<p>fragment</p>"}, "<p>fragment</p>HB"},
+		{"sniffed", "", []string{"<!DOCTYPE html><head></head>"}, "<!DOCTYPE html><head>H</head>B"},
+		{"other content type", "text/plain", []string{"</head></body>"}, "</head></body>"},
+		{"sniffed other content type", "", []string{"plain </head>"}, "plain </head>"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		inj := NewHTMLInjector(rec, func() (head, body []byte) { return []byte("H"), []byte("B") })
+		if test.contentType != "" {
+			inj.Header().Set("Content-Type", test.contentType)
+		}
+		for _, chunk := range test.chunks {
+			io.WriteString(inj, chunk)
+		}
+		inj.Close()
+
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("%s: body == %#v != %#v", test.name, got, test.body)
+		}
+	}
+}
+
+func TestHTMLInjectorLazySnippets(t *testing.T) {
+	var calls int
+	nonce := ""
+	snippets := func() (head, body []byte) {
+		calls++
+		return []byte("<script nonce=" + nonce + ">"), nil
+	}
+
+	rec := httptest.NewRecorder()
+	inj := NewHTMLInjector(rec, snippets)
+	inj.Header().Set("Content-Type", "image/png")
+	io.WriteString(inj, "</head>")
+	inj.Close()
+
+	if calls != 0 {
+		t.Errorf("snippets should not be called for other content types")
+	}
+
+	rec = httptest.NewRecorder()
+	inj = NewHTMLInjector(rec, snippets)
+	inj.Header().Set("Content-Type", "text/html")
+	nonce = "abc"
+	io.WriteString(inj, "<head></head>")
+	io.WriteString(inj, "<body></body>")
+	inj.Close()
+
+	if calls != 1 {
+		t.Errorf("snippets should be called once, got %d calls", calls)
+	}
+
+	if got, want := rec.Body.String(), "<head><script nonce=abc></head><body></body>"; got != want {
+		t.Errorf("body == %#v != %#v", got, want)
+	}
+}
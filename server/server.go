@@ -32,10 +32,13 @@ func errCatcher(err interface{}, w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusInternalServerError)
 }
 
+// DefaultStack returns the stack used by New. Its middleware is labeled
+// "catch", "prepare", "methodoverride" and "methodoverridebyfield", so that it
+// may be changed via the InsertBefore, InsertAfter, Replace and Remove methods of the stack.
 func DefaultStack() *stack.Stack {
 	return stack.New().
-		Use(mw.Catch(errCatcher)).
-		Use(mw.Prepare()).
-		Use(mw.MethodOverride()).
-		Use(mw.MethodOverrideByField("_method"))
+		Use(mw.Catch(errCatcher)).Label("catch").InPhase(stack.PhaseRecover).
+		Use(mw.Prepare()).Label("prepare").
+		Use(mw.MethodOverride()).Label("methodoverride").
+		Use(mw.MethodOverrideByField("_method")).Label("methodoverridebyfield")
 }
@@ -3,7 +3,10 @@
 package stack
 
 import (
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"runtime"
 )
 
 func New() (s *Stack) {
@@ -11,67 +14,126 @@ func New() (s *Stack) {
 }
 
 // Stack is a stack of middlewares that handle http requests
-type Stack []func(http.Handler) http.Handler
+type Stack struct {
+	entries      []*entry
+	instrumenter Instrumenter
+	errorHandler func(error, http.ResponseWriter, *http.Request)
+}
+
+// entry is a middleware inside the stack
+type entry struct {
+	desc Descriptor
+
+	// sub is set, if the middleware is a Stack itself
+	sub *Stack
+
+	// embedded is true, if the middleware is a Stack that has been added via UseWrapper
+	embedded bool
+
+	// cond is set, if the middleware has been added via UseIf or UseUnless
+	cond *conditional
+
+	// deps are the declared dependencies of the middleware
+	deps dependencies
+
+	fn func(http.Handler) http.Handler
+
+	// withError returns fn for middleware that returns errors, once the error handler is known
+	withError withError
+}
+
+// push adds the middleware mw with the given kind and wrapping function to the stack.
+// It must be called directly by the exported UseXXX methods in order to track the caller.
+func (s *Stack) push(kind Kind, mw interface{}, fn func(http.Handler) http.Handler) *Stack {
+	e := &entry{fn: fn}
+	e.desc.Kind = kind
+	e.desc.Phase = PhaseApp
+	e.desc.Name = nameOf(mw)
+	e.deps = dependenciesOf(mw)
+	if _, file, line, ok := runtime.Caller(2); ok {
+		e.desc.Caller = fmt.Sprintf("%s:%d", filepath.FromSlash(file), line)
+	}
+	switch x := mw.(type) {
+	case *Stack:
+		e.sub = x
+		e.embedded = true
+	case *Frozen:
+		e.sub = x.stack
+	case *conditional:
+		e.cond = x
+		e.sub, _ = x.mw.(*Stack)
+	}
+	s.entries = append(s.entries, e)
+	return s
+}
 
 // Use adds the given middleware to the middleware stack
 func (s *Stack) Use(mw Middleware) *Stack {
-	return s.UseWrapperFunc(mwHandler(mw))
+	return s.push(KindMiddleware, mw, mwHandler(mw))
 }
 
 // UseFunc adds the given function to the middleware stack
 func (s *Stack) UseFunc(fn func(wr http.ResponseWriter, req *http.Request, next http.Handler)) *Stack {
-	return s.UseWrapperFunc(mwHandlerFunc(fn).Middleware())
+	return s.push(KindMiddleware, fn, mwHandlerFunc(fn).Middleware())
 }
 
 // UseHandler adds the given handler as middleware to the stack.
 // the handler will be called before the next middleware
 func (s *Stack) UseHandler(mw http.Handler) *Stack {
-	return s.UseWrapper(before(mw.ServeHTTP))
+	return s.push(KindHandler, mw, before(mw.ServeHTTP).Wrap)
 }
 
 // UseHandlerFunc is like UseHandler but for http.HandlerFunc
 func (s *Stack) UseHandlerFunc(fn func(wr http.ResponseWriter, req *http.Request)) *Stack {
-	return s.UseWrapper(before(fn))
+	return s.push(KindHandler, fn, before(fn).Wrap)
 }
 
 // UseHandlerWithContext adds the given context handler as middleware to the stack.
 // the handler will be called before the next middleware
 func (s *Stack) UseHandlerWithContext(mw ContextHandler) *Stack {
-	return s.UseWrapper(before(ContextHandlerFunc(mw.ServeHTTP).ServeHTTP))
+	return s.push(KindContextHandler, mw, before(ContextHandlerFunc(mw.ServeHTTP).ServeHTTP).Wrap)
 }
 
 // UseHandlerFuncWithContext adds the given function as middleware to the stack.
 // the handler will be called before the next middleware
 func (s *Stack) UseHandlerFuncWithContext(fn func(c Contexter, w http.ResponseWriter, r *http.Request)) *Stack {
-	return s.UseWrapper(before(ContextHandlerFunc(fn).ServeHTTP))
+	return s.push(KindContextHandler, fn, before(ContextHandlerFunc(fn).ServeHTTP).Wrap)
 }
 
 // UseWithContext adds the context middleware to the middleware stack
 func (s *Stack) UseWithContext(mw ContextMiddleware) *Stack {
-	return s.UseWrapperFunc(mwCtxHandlerFunc(mw.ServeHTTP).Middleware())
+	return s.push(KindContextMiddleware, mw, mwCtxHandlerFunc(mw.ServeHTTP).Middleware())
 }
 
 // UseFuncWithContext adds the given function to the middleware stack
 func (s *Stack) UseFuncWithContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler)) *Stack {
-	return s.UseWrapperFunc(mwCtxHandlerFunc(fn).Middleware())
+	return s.push(KindContextMiddleware, fn, mwCtxHandlerFunc(fn).Middleware())
 }
 
-// UseWrapper adds the given wrapper to the middleware stack
+// UseWrapper adds the given wrapper to the middleware stack.
+// If the wrapper is a *Stack, it is described as embedded stack by Describe.
 func (s *Stack) UseWrapper(mw Wrapper) *Stack {
-	return s.UseWrapperFunc(mw.Wrap)
+	return s.push(KindWrapper, mw, mw.Wrap)
 }
 
 // UseWrapperFunc adds the given function to the middleware stack
 func (s *Stack) UseWrapperFunc(mw func(http.Handler) http.Handler) *Stack {
-	*s = append(*s, mw)
-	return s
+	return s.push(KindWrapper, mw, mw)
 }
 
-// Concat returns a new stack that has the middleware of the current stack concatenated with the middleware of the given stack
+// Concat returns a new stack that has the middleware of the current stack concatenated with the middleware of the given stack.
+// The new stack does not share any middleware with the given stacks, so changing one of them does not affect the others.
 func (s *Stack) Concat(st *Stack) *Stack {
-	// *s = append(*s, st...)
-	s3 := append(*s, (*st)...)
-	return &s3
+	entries := make([]*entry, 0, len(s.entries)+len(st.entries))
+	for _, e := range s.entries {
+		cp := *e
+		entries = append(entries, &cp)
+	}
+	for _, e := range st.entries {
+		cp := *e
+		entries = append(entries, &cp)
+	}
+	return &Stack{entries: entries, instrumenter: s.instrumenter, errorHandler: s.errorHandler}
 }
 
 // Wrap wraps the stack around the next handler and returns the resulting handler
@@ -80,11 +142,34 @@ func (s *Stack) Wrap(next http.Handler) http.Handler {
 }
 
 func (s *Stack) wrap(next http.Handler) http.Handler {
+	return s.wrapInstrumented(next, s.instrumenter)
+}
+
+// wrapInstrumented is like wrap but notifies the given Instrumenter (that might be nil). Stacks that are embedded
+// via UseWrapper are wrapped with their own Instrumenter or else with the given one.
+func (s *Stack) wrapInstrumented(next http.Handler, in Instrumenter) http.Handler {
 	if next == nil {
 		next = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 	}
-	for i := len(*s) - 1; i >= 0; i-- {
-		next = (*s)[i](next)
+	entries := s.ordered()
+	onError := s.onError()
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		switch {
+		case e.withError != nil:
+			next = e.withError(onError)(next)
+		case e.sub != nil && e.cond == nil && in != nil:
+			subIn := in
+			if e.sub.instrumenter != nil {
+				subIn = e.sub.instrumenter
+			}
+			next = e.sub.wrapInstrumented(next, subIn)
+		default:
+			next = e.fn(next)
+		}
+		if in != nil {
+			next = &instrumented{next, &e.desc, in}
+		}
 	}
 	return next
 }
@@ -111,3 +196,21 @@ func (s *Stack) WrapWithContext(app ContextHandler) http.Handler {
 func (s *Stack) WrapFuncWithContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request)) http.Handler {
 	return &contextHandler{s.Wrap(ContextHandlerFunc(fn))}
 }
+
+// HandlerWithRequestContext is like HandlerWithContext but passes the Contexter via the context.Context
+// of the request instead of the http.ResponseWriter.
+// Context middleware keeps working if some other middleware replaces the http.ResponseWriter.
+func (s *Stack) HandlerWithRequestContext() http.Handler {
+	return &requestContextHandler{s.wrap(nil)}
+}
+
+// WrapWithRequestContext is like WrapWithContext but passes the Contexter via the context.Context
+// of the request instead of the http.ResponseWriter.
+// It should be used instead of Wrap for the outermost stack and only there
+func (s *Stack) WrapWithRequestContext(app ContextHandler) http.Handler {
+	return &requestContextHandler{s.Wrap(ContextHandlerFunc(app.ServeHTTP))}
+}
+
+func (s *Stack) WrapFuncWithRequestContext(fn func(ctx Contexter, wr http.ResponseWriter, req *http.Request)) http.Handler {
+	return &requestContextHandler{s.Wrap(ContextHandlerFunc(fn))}
+}
@@ -4,6 +4,7 @@ package stack
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +29,52 @@ func TestStack(t *testing.T) {
 		assertResponse(t, rec, body, 200)
 	}
 }
+
+func TestDescribe(t *testing.T) {
+	inner := New().UseHandler(writeDebug("inner"))
+	s := New().
+		UseFunc(write("a").ServeHTTPNext).
+		UseWrapper(inner).
+		UseWithContext(setCtx("b"))
+
+	descs := s.Describe()
+
+	if len(descs) != 3 {
+		t.Fatalf("len(descs) == %d != 3", len(descs))
+	}
+
+	kinds := []Kind{KindMiddleware, KindWrapper, KindContextMiddleware}
+	for i, d := range descs {
+		if d.Kind != kinds[i] {
+			t.Errorf("descs[%d].Kind == %s != %s", i, d.Kind, kinds[i])
+		}
+		if !strings.Contains(d.Caller, "stack_test.go:") {
+			t.Errorf("descs[%d].Caller == %#v does not point to stack_test.go", i, d.Caller)
+		}
+	}
+
+	if got, want := descs[0].Name, "github.com/go-on/stack.write.ServeHTTPNext-fm"; got != want {
+		t.Errorf("descs[0].Name == %#v != %#v", got, want)
+	}
+
+	if got, want := descs[2].Name, "stack.setCtx"; got != want {
+		t.Errorf("descs[2].Name == %#v != %#v", got, want)
+	}
+
+	if len(descs[1].Sub) != 1 {
+		t.Fatalf("len(descs[1].Sub) == %d != 1", len(descs[1].Sub))
+	}
+
+	if got, want := descs[1].Sub[0].Name, `<writeDebug "inner">`; got != want {
+		t.Errorf("descs[1].Sub[0].Name == %#v != %#v", got, want)
+	}
+
+	lines := strings.Split(strings.TrimSpace(s.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("s.String() has %d lines, expected 4:\n%s", len(lines), s.String())
+	}
+
+	if !strings.HasPrefix(lines[2], "  Handler <writeDebug \"inner\"> (") {
+		t.Errorf("embedded stack not rendered indented: %#v", lines[2])
+	}
+}
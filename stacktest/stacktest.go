@@ -0,0 +1,371 @@
+/*
+Package stacktest provides a fluent harness to test a single middleware in isolation.
+
+The middleware is run with a fake next handler. Afterwards it can be checked, if the next handler
+was called, what it saw (request and context values) and what reached the client.
+
+	func TestSetUser(t *testing.T) {
+		stacktest.ContextMiddleware(t, setUser{}).
+			Request("GET", "/").
+			Header("Authorization", "Bearer xyz").
+			Run().
+			ExpectNextCalled().
+			ExpectNextValue(userKey, "peter").
+			ExpectStatus(200)
+	}
+
+For middleware that wraps the http.ResponseWriter (e.g. via responsewriter.Buffer or responsewriter.Peek),
+ExpectNextWriterWrapped, ExpectNextWriteHeldBack and ExpectNextWritePassedThrough check how the writes
+of the next handler were treated.
+*/
+package stacktest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/responsewriter"
+)
+
+// Harness runs a single middleware with a fake next handler. It is created via
+// Middleware, MiddlewareFunc, ContextMiddleware, ContextMiddlewareFunc, Wrapper or Stack.
+type Harness struct {
+	t              testing.TB
+	stack          *stack.Stack
+	method, path   string
+	body           string
+	header         http.Header
+	preload        []func(stack.Contexter)
+	next           http.Handler
+	requestContext bool
+}
+
+// Stack returns a Harness that runs the given stack
+func Stack(t testing.TB, s *stack.Stack) *Harness {
+	return &Harness{t: t, stack: s, method: "GET", path: "/", header: http.Header{}}
+}
+
+// Middleware returns a Harness that runs the given stack.Middleware
+func Middleware(t testing.TB, mw stack.Middleware) *Harness {
+	return Stack(t, stack.New().Use(mw))
+}
+
+// MiddlewareFunc returns a Harness that runs the given middleware function
+func MiddlewareFunc(t testing.TB, fn func(wr http.ResponseWriter, req *http.Request, next http.Handler)) *Harness {
+	return Stack(t, stack.New().UseFunc(fn))
+}
+
+// ContextMiddleware returns a Harness that runs the given stack.ContextMiddleware
+func ContextMiddleware(t testing.TB, mw stack.ContextMiddleware) *Harness {
+	return Stack(t, stack.New().UseWithContext(mw))
+}
+
+// ContextMiddlewareFunc returns a Harness that runs the given context middleware function
+func ContextMiddlewareFunc(t testing.TB, fn func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler)) *Harness {
+	return Stack(t, stack.New().UseFuncWithContext(fn))
+}
+
+// Wrapper returns a Harness that runs the given stack.Wrapper
+func Wrapper(t testing.TB, w stack.Wrapper) *Harness {
+	return Stack(t, stack.New().UseWrapper(w))
+}
+
+// Request sets the method and path of the request. The default is GET /
+func (h *Harness) Request(method, path string) *Harness {
+	h.method, h.path = method, path
+	return h
+}
+
+// Header adds a request header
+func (h *Harness) Header(key, val string) *Harness {
+	h.header.Add(key, val)
+	return h
+}
+
+// Body sets the request body
+func (h *Harness) Body(body string) *Harness {
+	h.body = body
+	return h
+}
+
+// Set preloads the Contexter with the given Swapper before the middleware runs
+func (h *Harness) Set(val stack.Swapper) *Harness {
+	h.preload = append(h.preload, func(ctx stack.Contexter) { ctx.Set(val) })
+	return h
+}
+
+// SetValue preloads the Contexter with the given value before the middleware runs
+func (h *Harness) SetValue(key, val interface{}) *Harness {
+	h.preload = append(h.preload, func(ctx stack.Contexter) { ctx.SetValue(key, val) })
+	return h
+}
+
+// RequestContext passes the Contexter via the context of the request (see stack.WrapWithRequestContext)
+// instead of the http.ResponseWriter
+func (h *Harness) RequestContext() *Harness {
+	h.requestContext = true
+	return h
+}
+
+// Next sets the handler that is called as next handler after the fake next has recorded the request.
+// By default the next handler writes nothing.
+func (h *Harness) Next(next http.Handler) *Harness {
+	h.next = next
+	return h
+}
+
+// NextResponds lets the next handler respond with the given status code, headers and body.
+// The headers are given as pairs of key and value.
+func (h *Harness) NextResponds(code int, body string, header ...string) *Harness {
+	return h.Next(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		for i := 0; i+1 < len(header); i += 2 {
+			wr.Header().Add(header[i], header[i+1])
+		}
+		wr.WriteHeader(code)
+		io.WriteString(wr, body)
+	}))
+}
+
+// Run serves the request and returns the Result
+func (h *Harness) Run() *Result {
+	res := &Result{t: h.t, Response: httptest.NewRecorder()}
+	client := responsewriter.NewRecorder(res.Response)
+
+	var body io.Reader
+	if h.body != "" {
+		body = strings.NewReader(h.body)
+	}
+	req := httptest.NewRequest(h.method, h.path, body)
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+
+	s := stack.New().UseFuncWithContext(func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		for _, fn := range h.preload {
+			fn(ctx)
+		}
+		res.stackWriter = wr
+		next.ServeHTTP(wr, req)
+	}).UseWrapper(h.stack)
+
+	next := func(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request) {
+		res.NextCalled = true
+		res.NextRequest = req
+		res.NextWriter = wr
+		res.NextContext = ctx.Snapshot()
+		if h.next != nil {
+			h.next.ServeHTTP(wr, req)
+		}
+		res.passedThrough = client.HeaderWritten()
+	}
+
+	wr := stack.WrapResponseWriter(res.Response, client)
+	if h.requestContext {
+		s.WrapFuncWithRequestContext(next).ServeHTTP(wr, req)
+	} else {
+		s.WrapFuncWithContext(next).ServeHTTP(wr, req)
+	}
+	return res
+}
+
+// Result is the result of running a Harness. The Expect methods report failures to the testing.TB of the Harness
+// and return the Result for chaining.
+type Result struct {
+	t testing.TB
+
+	// stackWriter is the http.ResponseWriter that was passed to the middleware
+	stackWriter http.ResponseWriter
+
+	// passedThrough tracks if the status code reached the client before the next handler returned
+	passedThrough bool
+
+	// NextCalled is true, if the next handler was called
+	NextCalled bool
+
+	// NextRequest is the request the next handler received
+	NextRequest *http.Request
+
+	// NextWriter is the http.ResponseWriter the next handler received
+	NextWriter http.ResponseWriter
+
+	// NextContext is a snapshot of the Contexter as the next handler saw it
+	NextContext stack.Contexter
+
+	// Response records what reached the client
+	Response *httptest.ResponseRecorder
+}
+
+// ExpectNextCalled expects the next handler to be called
+func (r *Result) ExpectNextCalled() *Result {
+	r.t.Helper()
+	if !r.NextCalled {
+		r.t.Errorf("next handler was not called")
+	}
+	return r
+}
+
+// ExpectNextNotCalled expects the next handler not to be called
+func (r *Result) ExpectNextNotCalled() *Result {
+	r.t.Helper()
+	if r.NextCalled {
+		r.t.Errorf("next handler was called")
+	}
+	return r
+}
+
+// nextCalled reports an error if the next handler was not called
+func (r *Result) nextCalled(what string) bool {
+	r.t.Helper()
+	if !r.NextCalled {
+		r.t.Errorf("can't check %s: next handler was not called", what)
+		return false
+	}
+	return true
+}
+
+// ExpectNextMethod expects the next handler to see the given request method
+func (r *Result) ExpectNextMethod(method string) *Result {
+	r.t.Helper()
+	if r.nextCalled("method") && r.NextRequest.Method != method {
+		r.t.Errorf("next handler saw method %#v, expected %#v", r.NextRequest.Method, method)
+	}
+	return r
+}
+
+// ExpectNextPath expects the next handler to see the given request path
+func (r *Result) ExpectNextPath(path string) *Result {
+	r.t.Helper()
+	if r.nextCalled("path") && r.NextRequest.URL.Path != path {
+		r.t.Errorf("next handler saw path %#v, expected %#v", r.NextRequest.URL.Path, path)
+	}
+	return r
+}
+
+// ExpectNextHeader expects the next handler to see the given request header
+func (r *Result) ExpectNextHeader(key, val string) *Result {
+	r.t.Helper()
+	if r.nextCalled("header "+key) && r.NextRequest.Header.Get(key) != val {
+		r.t.Errorf("next handler saw header %s: %#v, expected %#v", key, r.NextRequest.Header.Get(key), val)
+	}
+	return r
+}
+
+// ExpectNextValue expects the next handler to see the given value for the given key inside the Contexter
+func (r *Result) ExpectNextValue(key, val interface{}) *Result {
+	r.t.Helper()
+	if !r.nextCalled("context value") {
+		return r
+	}
+	got, has := r.NextContext.GetValue(key)
+	switch {
+	case !has:
+		r.t.Errorf("next handler saw no context value for %v, expected %#v", key, val)
+	case !reflect.DeepEqual(got, val):
+		r.t.Errorf("next handler saw context value %#v for %v, expected %#v", got, key, val)
+	}
+	return r
+}
+
+// ExpectNextGet expects the next handler to see a Swapper of the type of target inside the Contexter.
+// If there is one, target is swapped with it and may be checked afterwards.
+func (r *Result) ExpectNextGet(target stack.Swapper) *Result {
+	r.t.Helper()
+	if r.nextCalled("context") && !r.NextContext.Get(target) {
+		r.t.Errorf("next handler saw no %T inside the context", target)
+	}
+	return r
+}
+
+// ExpectNextWriterWrapped expects the next handler to receive a replaced http.ResponseWriter
+// (e.g. a responsewriter.Peek or responsewriter.Buffer) instead of the one of the stack.
+func (r *Result) ExpectNextWriterWrapped() *Result {
+	r.t.Helper()
+	if !r.nextCalled("writer") {
+		return r
+	}
+	if unwrap(r.NextWriter) == unwrap(r.stackWriter) {
+		r.t.Errorf("next handler received the http.ResponseWriter of the stack, expected a wrapper")
+	}
+	return r
+}
+
+// ExpectNextWriteHeldBack expects that nothing the next handler wrote reached the client before
+// the next handler returned, as with a responsewriter.Buffer or a responsewriter.Peek that did not proceed.
+// What reached the client afterwards can be checked via ExpectStatus, ExpectHeader and ExpectBody.
+func (r *Result) ExpectNextWriteHeldBack() *Result {
+	r.t.Helper()
+	if r.nextCalled("writes") && r.passedThrough {
+		r.t.Errorf("the response of the next handler reached the client before it returned, expected it to be held back")
+	}
+	return r
+}
+
+// ExpectNextWritePassedThrough expects that the status code and body written by the next handler
+// reached the client before the next handler returned, as with a responsewriter.Peek that proceeds
+// or a responsewriter.Recorder.
+func (r *Result) ExpectNextWritePassedThrough() *Result {
+	r.t.Helper()
+	if r.nextCalled("writes") && !r.passedThrough {
+		r.t.Errorf("the response of the next handler did not reach the client before it returned, expected it to pass through")
+	}
+	return r
+}
+
+// unwrap returns the outer http.ResponseWriter of writers created via stack.WrapResponseWriter
+func unwrap(wr http.ResponseWriter) http.ResponseWriter {
+	if u, ok := wr.(stack.Unwrapper); ok {
+		return u.Unwrap()
+	}
+	return wr
+}
+
+// ExpectStatus expects the given status code to reach the client
+func (r *Result) ExpectStatus(code int) *Result {
+	r.t.Helper()
+	if r.Response.Code != code {
+		r.t.Errorf("status code %d reached the client, expected %d", r.Response.Code, code)
+	}
+	return r
+}
+
+// ExpectHeader expects the given response header to reach the client
+func (r *Result) ExpectHeader(key, val string) *Result {
+	r.t.Helper()
+	if got := r.Response.Header().Get(key); got != val {
+		r.t.Errorf("header %s: %#v reached the client, expected %#v", key, got, val)
+	}
+	return r
+}
+
+// ExpectBody expects the given body to reach the client
+func (r *Result) ExpectBody(body string) *Result {
+	r.t.Helper()
+	if got := r.Response.Body.String(); got != body {
+		r.t.Errorf("body %#v reached the client, expected %#v", got, body)
+	}
+	return r
+}
+
+// ExpectBodyContains expects the body that reached the client to contain the given string
+func (r *Result) ExpectBodyContains(s string) *Result {
+	r.t.Helper()
+	if got := r.Response.Body.String(); !strings.Contains(got, s) {
+		r.t.Errorf("body %#v reached the client, expected it to contain %#v", got, s)
+	}
+	return r
+}
+
+// ExpectNothingWritten expects that nothing reached the client, e.g. because a buffering
+// middleware did not flush the response
+func (r *Result) ExpectNothingWritten() *Result {
+	r.t.Helper()
+	if r.Response.Body.Len() != 0 || len(r.Response.Header()) != 0 || r.Response.Flushed {
+		r.t.Errorf("response reached the client: headers %v, body %#v", r.Response.Header(), r.Response.Body.String())
+	}
+	return r
+}
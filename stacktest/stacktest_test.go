@@ -0,0 +1,127 @@
+package stacktest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/mw"
+	"github.com/go-on/stack/responsewriter"
+)
+
+type userKey struct{}
+
+func setUser(ctx stack.Contexter, wr http.ResponseWriter, req *http.Request, next http.Handler) {
+	if req.Header.Get("Authorization") == "" {
+		wr.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	ctx.SetValue(userKey{}, "peter")
+	next.ServeHTTP(wr, req)
+}
+
+func TestContextMiddleware(t *testing.T) {
+	ContextMiddlewareFunc(t, setUser).
+		Request("POST", "/users").
+		Header("Authorization", "Bearer xyz").
+		SetValue("preloaded", 42).
+		NextResponds(http.StatusCreated, "created", "X-Next", "yes").
+		Run().
+		ExpectNextCalled().
+		ExpectNextMethod("POST").
+		ExpectNextPath("/users").
+		ExpectNextHeader("Authorization", "Bearer xyz").
+		ExpectNextValue(userKey{}, "peter").
+		ExpectNextValue("preloaded", 42).
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader("X-Next", "yes").
+		ExpectBody("created")
+
+	ContextMiddlewareFunc(t, setUser).
+		RequestContext().
+		Run().
+		ExpectNextNotCalled().
+		ExpectStatus(http.StatusUnauthorized)
+}
+
+func TestMiddlewareWithPeek(t *testing.T) {
+	res := Middleware(t, mw.ContentType("text/plain")).
+		NextResponds(http.StatusOK, "hello").
+		Run().
+		ExpectNextCalled().
+		ExpectNextWriterWrapped().
+		ExpectNextWritePassedThrough().
+		ExpectHeader("Content-Type", "text/plain").
+		ExpectBody("hello")
+
+	if _, ok := res.NextWriter.(stack.Contexter); !ok {
+		t.Errorf("next should receive a Contexter")
+	}
+}
+
+func TestMiddlewareWithBuffer(t *testing.T) {
+	MiddlewareFunc(t, func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		buf := responsewriter.NewBuffer(wr)
+		next.ServeHTTP(stack.WrapResponseWriter(wr, buf), req)
+		buf.FlushAll()
+	}).
+		NextResponds(http.StatusCreated, "created").
+		Run().
+		ExpectNextWriterWrapped().
+		ExpectNextWriteHeldBack().
+		ExpectStatus(http.StatusCreated).
+		ExpectBody("created")
+
+	MiddlewareFunc(t, func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		next.ServeHTTP(stack.WrapResponseWriter(wr, responsewriter.NewBuffer(wr)), req)
+	}).
+		NextResponds(http.StatusCreated, "created").
+		Run().
+		ExpectNextWriteHeldBack().
+		ExpectNothingWritten()
+}
+
+type failingT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failingT) Helper() {}
+
+func (f *failingT) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
+
+func TestFailures(t *testing.T) {
+	ft := &failingT{TB: t}
+	MiddlewareFunc(ft, func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		next.ServeHTTP(wr, req)
+	}).Run().ExpectNextWriterWrapped()
+
+	if !ft.failed {
+		t.Errorf("ExpectNextWriterWrapped should fail if the writer was not replaced")
+	}
+
+	ft = &failingT{TB: t}
+	Wrapper(ft, stack.New()).Run().ExpectNextNotCalled().ExpectNothingWritten()
+
+	if !ft.failed {
+		t.Errorf("ExpectNextNotCalled should fail if next was called")
+	}
+
+	ft = &failingT{TB: t}
+	Wrapper(ft, stack.New()).NextResponds(http.StatusOK, "hello").Run().ExpectNextWriteHeldBack()
+
+	if !ft.failed {
+		t.Errorf("ExpectNextWriteHeldBack should fail if the response passed through")
+	}
+
+	ft = &failingT{TB: t}
+	MiddlewareFunc(ft, func(wr http.ResponseWriter, req *http.Request, next http.Handler) {
+		next.ServeHTTP(stack.WrapResponseWriter(wr, responsewriter.NewBuffer(wr)), req)
+	}).NextResponds(http.StatusOK, "hello").Run().ExpectNextWritePassedThrough()
+
+	if !ft.failed {
+		t.Errorf("ExpectNextWritePassedThrough should fail if the response was held back")
+	}
+}
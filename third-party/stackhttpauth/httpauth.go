@@ -35,6 +35,11 @@ func (d *digest) ServeHTTP(ctx stack.Contexter, wr http.ResponseWriter, req *htt
 	d.auth.Wrap(fn).ServeHTTP(wr, req)
 }
 
+// Provides declares that the middleware provides the AuthenticatedRequest (see stack.Provider)
+func (d *digest) Provides() []interface{} {
+	return []interface{}{&AuthenticatedRequest{}}
+}
+
 // NewDigest returns a middleware that authenticates via auth.NewDigestAuthenticator
 // and saves the resulting *auth.AuthenticatedRequest in the Contexter (response writer).
 func NewDigest(realm string, secrets func(user, realm string) string) *digest {
@@ -54,6 +59,11 @@ func (d *basic) ServeHTTP(ctx stack.Contexter, wr http.ResponseWriter, req *http
 	d.auth.Wrap(fn).ServeHTTP(wr, req)
 }
 
+// Provides declares that the middleware provides the AuthenticatedRequest (see stack.Provider)
+func (d *basic) Provides() []interface{} {
+	return []interface{}{&AuthenticatedRequest{}}
+}
+
 // NewBasic returns a middleware that authenticates via auth.NewBasicAuthenticator
 // and saves the resulting *auth.AuthenticatedRequest in the Contexter (response writer).
 func NewBasic(realm string, secrets func(user, realm string) string) *basic {
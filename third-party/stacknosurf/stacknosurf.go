@@ -35,6 +35,11 @@ func (SetToken) ServeHTTP(ctx stack.Contexter, rw http.ResponseWriter, req *http
 	next.ServeHTTP(rw, req)
 }
 
+// Provides declares that SetToken provides the Token (see stack.Provider)
+func (SetToken) Provides() []interface{} {
+	return []interface{}{new(Token)}
+}
+
 // CheckToken is a middleware that checks the token via the github.com/justinas/nosurf
 // package. Its attributes relate to the corresponding nosurf options. If they are nil,
 // they are not set.
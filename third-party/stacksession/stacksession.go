@@ -39,6 +39,11 @@ func (s *store) ServeHTTP(ctx stack.Contexter, rw http.ResponseWriter, req *http
 	next.ServeHTTP(rw, req)
 }
 
+// Provides declares that the store provides the Session (see stack.Provider)
+func (s *store) Provides() []interface{} {
+	return []interface{}{&Session{}}
+}
+
 func NewStore(st sessions.Store, name string) *store {
 	return &store{Store: st, Name: name}
 }
@@ -58,6 +63,12 @@ func (s saveAndClear) ServeHTTP(ctx stack.Contexter, rw http.ResponseWriter, req
 	next.ServeHTTP(rw, req)
 }
 
+// RequiresAfter declares that a Session must be provided by any middleware of the stack,
+// e.g. via NewStore (see stack.DeferredRequirer)
+func (s saveAndClear) RequiresAfter() []interface{} {
+	return []interface{}{&Session{}}
+}
+
 // SaveAndClear saves the session and clears up any references to the request.
 // it should be used at the beginning of the mw chain (but after the Contexter)
 var SaveAndClear = saveAndClear{}
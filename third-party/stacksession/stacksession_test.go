@@ -0,0 +1,24 @@
+package stacksession_test
+
+import (
+	"testing"
+
+	"github.com/go-on/stack"
+	"github.com/go-on/stack/third-party/stacksession"
+)
+
+func TestDependencies(t *testing.T) {
+	s := stack.New().
+		UseWithContext(stacksession.SaveAndClear).
+		UseWithContext(stacksession.NewStore(store, "my-session-name")).
+		UseFuncWithContext(printFlashes)
+
+	if err := s.ValidateWithContext(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	s = stack.New().UseWithContext(stacksession.SaveAndClear)
+	if errs, _ := s.ValidateWithContext().(stack.ErrDependencies); len(errs) != 1 {
+		t.Errorf("SaveAndClear without store should miss the Session, got %v", errs)
+	}
+}
@@ -0,0 +1,81 @@
+package stack
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// ErrContextRequired is the error returned by Validate if middleware of the stack needs a Contexter
+type ErrContextRequired struct {
+	// Middleware describes the middleware that needs a Contexter
+	Middleware []Descriptor
+}
+
+// Error returns the error message, listing the middleware that needs a Contexter and where it was added
+func (e ErrContextRequired) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("stack needs a Contexter (use WrapWithContext, HandlerWithContext or the RequestContext variants) for:")
+	for _, d := range e.Middleware {
+		buf.WriteString("\n  ")
+		buf.WriteString(d.String())
+	}
+	return buf.String()
+}
+
+// needsContext returns true, if the middleware of the given kind needs a Contexter
+func (k Kind) needsContext() bool {
+	return k == KindContextMiddleware || k == KindContextHandler
+}
+
+// contextMiddleware returns the descriptors of all middleware that needs a Contexter,
+// including the middleware of embedded stacks
+func (s *Stack) contextMiddleware() (descs []Descriptor) {
+	for _, e := range s.entries {
+		if e.desc.Kind.needsContext() {
+			descs = append(descs, e.desc)
+		}
+		if e.sub != nil {
+			descs = append(descs, e.sub.contextMiddleware()...)
+		}
+	}
+	return
+}
+
+// RequiresContext returns true, if any middleware of the stack (or of an embedded stack) needs a Contexter.
+// Such a stack must be served via WrapWithContext, HandlerWithContext etc.
+// or be embedded into a stack that is served that way.
+func (s *Stack) RequiresContext() bool {
+	return len(s.contextMiddleware()) > 0
+}
+
+// Validate checks if the stack may be served without a Contexter, i.e. via Wrap, WrapFunc or Handler.
+// If it has middleware that needs a Contexter, an ErrContextRequired is returned.
+// Also the declared dependencies of the middleware are checked like ValidateWithContext does, except that
+// middleware that returns errors needs no ErrorHandling, since its errors are written without a Contexter.
+func (s *Stack) Validate() error {
+	if descs := s.contextMiddleware(); len(descs) > 0 {
+		return ErrContextRequired{descs}
+	}
+	return s.checkDependencies(nil, false)
+}
+
+// MustWrap is like Wrap but panics if Validate returns an error
+func (s *Stack) MustWrap(next http.Handler) http.Handler {
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+	return s.Wrap(next)
+}
+
+// MustWrapFunc is like WrapFunc but panics if Validate returns an error
+func (s *Stack) MustWrapFunc(fn func(wr http.ResponseWriter, req *http.Request)) http.Handler {
+	return s.MustWrap(http.HandlerFunc(fn))
+}
+
+// MustHandler is like Handler but panics if Validate returns an error
+func (s *Stack) MustHandler() http.Handler {
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+	return s.Handler()
+}
@@ -0,0 +1,57 @@
+package stack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	plain := New().UseHandler(write("a")).UseWrapper(New().UseHandler(write("b")))
+
+	if plain.RequiresContext() {
+		t.Errorf("plain stack should not require a context")
+	}
+
+	if err := plain.Validate(); err != nil {
+		t.Errorf("unexpected error for plain stack: %v", err)
+	}
+
+	rec, req := newTestRequest("GET", "/")
+	plain.MustHandler().ServeHTTP(rec, req)
+	assertResponse(t, rec, "ab", 200)
+
+	withCtx := New().
+		UseHandler(write("a")).
+		UseWrapper(New().UseFuncWithContext(writeCtxNext)).
+		UseHandlerFuncWithContext(writeCtx)
+
+	if !withCtx.RequiresContext() {
+		t.Errorf("stack with context middleware should require a context")
+	}
+
+	err := withCtx.Validate()
+	errCtx, ok := err.(ErrContextRequired)
+	if !ok {
+		t.Fatalf("expected ErrContextRequired, got %#v", err)
+	}
+
+	if len(errCtx.Middleware) != 2 {
+		t.Fatalf("len(errCtx.Middleware) == %d != 2", len(errCtx.Middleware))
+	}
+
+	if got, want := errCtx.Middleware[0].Name, "github.com/go-on/stack.writeCtxNext"; got != want {
+		t.Errorf("errCtx.Middleware[0].Name == %#v != %#v", got, want)
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "ContextHandler github.com/go-on/stack.writeCtx (") ||
+		!strings.Contains(msg, "validate_test.go:") {
+		t.Errorf("error message lacks middleware and location: %s", msg)
+	}
+
+	defer func() {
+		if _, ok := recover().(ErrContextRequired); !ok {
+			t.Errorf("MustHandler should panic with ErrContextRequired")
+		}
+	}()
+	withCtx.MustHandler()
+}